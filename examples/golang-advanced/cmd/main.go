@@ -13,6 +13,7 @@ import (
 
 	"example/client"
 	"example/config"
+	"example/decoding"
 	"example/handlers"
 	"example/utils"
 )
@@ -28,6 +29,12 @@ func main() {
 		log.Fatalf("Invalid configuration: %v", err)
 	}
 
+	// Load IDLs for decoding instructions and accounts
+	dec, err := decoding.NewDecoder(cfg.IDLDirectory)
+	if err != nil {
+		log.Fatalf("Failed to load decoders: %v", err)
+	}
+
 	// Set up signal handling for graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -72,11 +79,11 @@ func main() {
 			switch choice {
 			case "1":
 				client.HandleSubscription(ctx, func() error {
-					return handlers.SubscribeToFilteredTransactions(ctx, eventClient, cfg)
+					return handlers.SubscribeToFilteredTransactions(ctx, eventClient, cfg, dec)
 				})
 			case "2":
 				client.HandleSubscription(ctx, func() error {
-					return handlers.SubscribeToAccountUpdates(ctx, eventClient)
+					return handlers.SubscribeToAccountUpdates(ctx, eventClient, dec)
 				})
 			case "3":
 				client.HandleSubscription(ctx, func() error {
@@ -84,7 +91,7 @@ func main() {
 				})
 			case "4":
 				client.HandleSubscription(ctx, func() error {
-					return handlers.SubscribeToWalletTransactions(ctx, eventClient)
+					return handlers.SubscribeToWalletTransactions(ctx, eventClient, dec)
 				})
 			case "5":
 				fmt.Println("Exiting...")
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"example/filter"
 	"example/types"
 )
 
@@ -43,6 +44,9 @@ func ValidateConfig(config *types.Config) error {
 	if config.AuthToken == "" {
 		return fmt.Errorf("auth token is required")
 	}
+	if _, err := filter.ParseMode(config.FilterMode); err != nil {
+		return err
+	}
 	return nil
 }
 
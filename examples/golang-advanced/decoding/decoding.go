@@ -0,0 +1,117 @@
+package decoding
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mr-tron/base58"
+	"github.com/thorlabsDev/ThorStreamer/sdks/go/decoder"
+
+	pb "example/proto"
+	"example/utils"
+)
+
+// Decoder decodes instructions of programs with a loaded Anchor IDL, and
+// accounts of those programs and of the programs the SDK knows
+type Decoder struct {
+	registry *decoder.Registry
+}
+
+// NewDecoder loads every Anchor IDL JSON file in idlDir. An empty idlDir
+// loads none.
+func NewDecoder(idlDir string) (*Decoder, error) {
+	d := &Decoder{registry: decoder.DefaultRegistry()}
+	if idlDir == "" {
+		return d, nil
+	}
+
+	entries, err := os.ReadDir(idlDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IDL directory: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(idlDir, entry.Name())
+		idl, err := decoder.LoadIDL(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load IDL: %v", err)
+		}
+		if err := d.registry.RegisterIDL(idl); err != nil {
+			return nil, fmt.Errorf("failed to register IDL %s: %v", path, err)
+		}
+	}
+	return d, nil
+}
+
+// Instruction decodes one instruction given the transaction's account keys.
+// It returns nil when the program has no IDL, the data does not match it or
+// an account index is outside the keys, such as for an unresolved lookup.
+func (d *Decoder) Instruction(keys []string, ix *pb.CompiledInstruction) *decoder.DecodedInstruction {
+	if d == nil || ix == nil || int(ix.ProgramIdIndex) >= len(keys) {
+		return nil
+	}
+	programID := keys[ix.ProgramIdIndex]
+	if _, ok := d.registry.IDL(programID); !ok {
+		return nil
+	}
+
+	accounts := make([]string, 0, len(ix.Accounts))
+	for _, index := range ix.Accounts {
+		if int(index) >= len(keys) {
+			return nil
+		}
+		accounts = append(accounts, keys[index])
+	}
+	decoded, err := d.registry.DecodeInstruction(programID, ix.Data, accounts)
+	if err != nil {
+		return nil
+	}
+	return decoded
+}
+
+// Instructions decodes the top-level and inner instructions of a
+// transaction whose programs have an IDL
+func (d *Decoder) Instructions(tx *pb.TransactionEvent) []*decoder.DecodedInstruction {
+	if d == nil || tx.Transaction == nil || tx.Transaction.Message == nil {
+		return nil
+	}
+	keys := utils.AccountKeys(tx.Transaction.Message)
+
+	var decoded []*decoder.DecodedInstruction
+	for _, ix := range tx.Transaction.Message.Instructions {
+		if dix := d.Instruction(keys, ix); dix != nil {
+			decoded = append(decoded, dix)
+		}
+	}
+	if tx.TransactionStatusMeta != nil {
+		for _, inner := range tx.TransactionStatusMeta.InnerInstructions {
+			for _, ix := range inner.Instructions {
+				if dix := d.Instruction(keys, ix.Instruction); dix != nil {
+					decoded = append(decoded, dix)
+				}
+			}
+		}
+	}
+	return decoded
+}
+
+// Account decodes an account's data into its type name and fields. It
+// returns false when the owner has no matching decoder.
+func (d *Decoder) Account(account *pb.SubscribeUpdateAccountInfo) (string, map[string]any, bool) {
+	if d == nil {
+		return "", nil, false
+	}
+	v, err := d.registry.Decode(base58.Encode(account.Owner), account.Data)
+	if err != nil {
+		return "", nil, false
+	}
+	if acc, ok := v.(*decoder.DecodedAccount); ok {
+		return acc.Name, acc.Fields, true
+	}
+	name := strings.TrimPrefix(fmt.Sprintf("%T", v), "*decoder.")
+	return name, decoder.Fields(v), true
+}
@@ -1,14 +1,38 @@
 package filter
 
 import (
-	"strings"
+	"fmt"
 	"sync"
 
 	pb "example/proto"
 	"example/types"
-	"github.com/mr-tron/base58"
+	"example/utils"
 )
 
+// Mode selects which transactions match a program filter
+type Mode string
+
+const (
+	// ModeInvoked matches transactions that invoke a filtered program, at the
+	// top level or through CPI
+	ModeInvoked Mode = "invoked"
+	// ModeTouched matches transactions that mention a filtered program among
+	// their account keys, whether or not they invoke it
+	ModeTouched Mode = "touched"
+)
+
+// ParseMode parses a filter mode, defaulting to ModeInvoked when empty
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "", ModeInvoked:
+		return ModeInvoked, nil
+	case ModeTouched:
+		return ModeTouched, nil
+	default:
+		return "", fmt.Errorf("unknown filter mode %q, want %q or %q", s, ModeInvoked, ModeTouched)
+	}
+}
+
 // NewFilter creates a new transaction filter
 func NewFilter(config *types.Config) *Filter {
 	programFilters := make(map[string]bool)
@@ -16,8 +40,12 @@ func NewFilter(config *types.Config) *Filter {
 		programFilters[program] = true
 	}
 
+	// ValidateConfig rejects unknown modes, so this only fills the default
+	mode, _ := ParseMode(config.FilterMode)
+
 	return &Filter{
 		programFilters: programFilters,
+		mode:           mode,
 		includeVote:    config.IncludeVote,
 		includeFailed:  config.IncludeFailed,
 	}
@@ -26,91 +54,110 @@ func NewFilter(config *types.Config) *Filter {
 // Filter struct moved from types to here
 type Filter struct {
 	programFilters map[string]bool
+	mode           Mode
 	includeVote    bool
 	includeFailed  bool
 	mu             sync.RWMutex
 }
 
+// Mode returns the filter's matching mode
+func (f *Filter) Mode() Mode {
+	return f.mode
+}
+
 // WantsTransaction checks if a transaction matches the filter criteria
 func (f *Filter) WantsTransaction(tx *pb.TransactionEvent) bool {
+	_, ok := f.Match(tx)
+	return ok
+}
+
+// Match checks if a transaction matches the filter criteria, returning the
+// first filtered program it matched on. With no program filters it returns
+// the first invoked program.
+func (f *Filter) Match(tx *pb.TransactionEvent) (string, bool) {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
 	// Check vote transaction filter
 	if tx.IsVote && !f.includeVote {
-		return false
+		return "", false
 	}
 
 	// Check failed transaction filter
 	if tx.TransactionStatusMeta != nil && tx.TransactionStatusMeta.IsStatusErr && !f.includeFailed {
-		return false
+		return "", false
 	}
 
+	programIDs := InvokedPrograms(tx)
+
 	// If no program filters are set, accept all transactions
 	if len(f.programFilters) == 0 {
-		return true
+		if len(programIDs) > 0 {
+			return programIDs[0], true
+		}
+		return "", true
 	}
 
-	// Extract program IDs from the transaction
-	programIDs := ExtractProgramIDs(tx)
+	if f.mode == ModeTouched {
+		programIDs = MentionedAccounts(tx)
+	}
 
 	// Check if any program in the transaction matches our filters
 	for _, programID := range programIDs {
 		if f.programFilters[programID] {
-			return true
+			return programID, true
 		}
 	}
 
-	return false
+	return "", false
 }
 
-// ExtractProgramIDs extracts all program IDs from a transaction
-func ExtractProgramIDs(tx *pb.TransactionEvent) []string {
-	programIDs := make(map[string]bool)
+// InvokedPrograms returns the programs a transaction invokes, from the
+// program ID index of its top-level and inner instructions, in the order
+// they are first invoked. Indexes past the static account keys refer to
+// addresses loaded from lookup tables.
+func InvokedPrograms(tx *pb.TransactionEvent) []string {
+	if tx.Transaction == nil || tx.Transaction.Message == nil {
+		return nil
+	}
+	msg := tx.Transaction.Message
+	keys := utils.AccountKeys(msg)
 
-	if tx.Transaction != nil && tx.Transaction.Message != nil {
-		msg := tx.Transaction.Message
-		// Get program IDs from account keys
-		for _, key := range msg.AccountKeys {
-			programIDs[base58.Encode(key)] = true
+	inner := make(map[uint32][]*pb.InnerInstruction)
+	if tx.TransactionStatusMeta != nil {
+		for _, ixs := range tx.TransactionStatusMeta.InnerInstructions {
+			inner[ixs.Index] = append(inner[ixs.Index], ixs.Instructions...)
 		}
+	}
 
-		// Add program IDs from instructions
-		for _, ix := range msg.Instructions {
-			if int(ix.ProgramIdIndex) < len(msg.AccountKeys) {
-				programID := base58.Encode(msg.AccountKeys[ix.ProgramIdIndex])
-				programIDs[programID] = true
-			}
+	seen := make(map[string]bool)
+	var programIDs []string
+	add := func(ix *pb.CompiledInstruction) {
+		if ix == nil || int(ix.ProgramIdIndex) >= len(keys) {
+			return
 		}
-
-		// Add program IDs from loaded addresses if it's v0 transaction
-		if msg.Version == 1 && msg.LoadedAddresses != nil { // v0 transaction
-			for _, key := range msg.LoadedAddresses.Writable {
-				programIDs[base58.Encode(key)] = true
-			}
-			for _, key := range msg.LoadedAddresses.Readonly {
-				programIDs[base58.Encode(key)] = true
-			}
+		programID := keys[ix.ProgramIdIndex]
+		if !seen[programID] {
+			seen[programID] = true
+			programIDs = append(programIDs, programID)
 		}
 	}
 
-	// Extract program IDs from logs
-	if tx.TransactionStatusMeta != nil {
-		for _, log_ := range tx.TransactionStatusMeta.LogMessages {
-			if strings.Contains(log_, "Program ") && strings.Contains(log_, "invoke [") {
-				parts := strings.Split(log_, "Program ")
-				if len(parts) > 1 {
-					programID := strings.Split(parts[1], " ")[0]
-					programIDs[programID] = true
-				}
-			}
+	for i, ix := range msg.Instructions {
+		add(ix)
+		for _, innerIx := range inner[uint32(i)] {
+			add(innerIx.Instruction)
 		}
 	}
+	return programIDs
+}
 
-	// Convert map to slice
-	result := make([]string, 0, len(programIDs))
-	for programID := range programIDs {
-		result = append(result, programID)
+// MentionedAccounts returns every account a transaction mentions: its
+// static account keys and the addresses loaded from lookup tables. Programs
+// appear here whether or not they are invoked.
+func MentionedAccounts(tx *pb.TransactionEvent) []string {
+	if tx.Transaction == nil {
+		return nil
 	}
-	return result
+	return utils.AccountKeys(tx.Transaction.Message)
 }
@@ -4,8 +4,9 @@ go 1.23.2
 
 require (
 	github.com/mr-tron/base58 v1.2.0
+	github.com/thorlabsDev/ThorStreamer/sdks/go v0.0.0-00010101000000-000000000000
 	google.golang.org/grpc v1.75.1
-	google.golang.org/protobuf v1.36.9
+	google.golang.org/protobuf v1.36.10
 )
 
 require (
@@ -14,3 +15,5 @@ require (
 	golang.org/x/text v0.26.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250707201910-8d1bb00bc6a7 // indirect
 )
+
+replace github.com/thorlabsDev/ThorStreamer/sdks/go => ../../sdks/go
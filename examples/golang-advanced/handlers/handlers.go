@@ -9,6 +9,7 @@ import (
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	"example/decoding"
 	"example/filter"
 	"example/logger"
 	"example/printer"
@@ -18,7 +19,7 @@ import (
 )
 
 // SubscribeToFilteredTransactions subscribes to filtered transactions
-func SubscribeToFilteredTransactions(ctx context.Context, client pb.EventPublisherClient, config *types.Config) error {
+func SubscribeToFilteredTransactions(ctx context.Context, client pb.EventPublisherClient, config *types.Config, dec *decoding.Decoder) error {
 	txFilter := filter.NewFilter(config)
 	sigLogger, err := logger.NewSignatureLogger(config.SignatureLogFile)
 	if err != nil {
@@ -31,7 +32,7 @@ func SubscribeToFilteredTransactions(ctx context.Context, client pb.EventPublish
 		return fmt.Errorf("failed to subscribe to transactions: %v", err)
 	}
 
-	fmt.Printf("\n📡 Monitoring transactions for programs: %v\n", config.ProgramFilters)
+	fmt.Printf("\n📡 Monitoring transactions for programs (%s): %v\n", txFilter.Mode(), config.ProgramFilters)
 	fmt.Printf("📝 Logging signatures to: %s\n", config.SignatureLogFile)
 	fmt.Println("-------------------------------------------")
 
@@ -57,28 +58,29 @@ func SubscribeToFilteredTransactions(ctx context.Context, client pb.EventPublish
 
 			tx := txWrapper.Transaction
 
-			if !txFilter.WantsTransaction(tx) {
+			// Match also returns the program ID to log
+			primaryProgramID, ok := txFilter.Match(tx)
+			if !ok {
 				continue
 			}
-
-			// Extract program ID for logging
-			programIDs := filter.ExtractProgramIDs(tx)
-			primaryProgramID := "unknown"
-			if len(programIDs) > 0 {
-				primaryProgramID = programIDs[0]
+			if primaryProgramID == "" {
+				primaryProgramID = "unknown"
 			}
 
+			decoded := dec.Instructions(tx)
+
 			// Log signature
 			if err := sigLogger.LogSignature(
 				tx.Signature,
 				tx.Slot,
 				primaryProgramID,
 				tx.TransactionStatusMeta != nil && !tx.TransactionStatusMeta.IsStatusErr,
+				decoded,
 			); err != nil {
 				log.Printf("Failed to log signature: %v", err)
 			}
 
-			printer.PrintTransaction(tx)
+			printer.PrintTransaction(tx, decoded)
 		default:
 			// Not a transaction event, skip
 			continue
@@ -87,7 +89,7 @@ func SubscribeToFilteredTransactions(ctx context.Context, client pb.EventPublish
 }
 
 // SubscribeToWalletTransactions subscribes to wallet-specific transactions
-func SubscribeToWalletTransactions(ctx context.Context, client pb.EventPublisherClient) error {
+func SubscribeToWalletTransactions(ctx context.Context, client pb.EventPublisherClient, dec *decoding.Decoder) error {
 	wallets, err := utils.GetUserWallets()
 	if err != nil {
 		return err
@@ -130,7 +132,7 @@ func SubscribeToWalletTransactions(ctx context.Context, client pb.EventPublisher
 				continue
 			}
 
-			printer.PrintDetailedTransaction(txWrapper.Transaction)
+			printer.PrintDetailedTransaction(txWrapper.Transaction, dec)
 		default:
 			continue
 		}
@@ -138,7 +140,7 @@ func SubscribeToWalletTransactions(ctx context.Context, client pb.EventPublisher
 }
 
 // SubscribeToAccountUpdates subscribes to account updates
-func SubscribeToAccountUpdates(ctx context.Context, client pb.EventPublisherClient) error {
+func SubscribeToAccountUpdates(ctx context.Context, client pb.EventPublisherClient, dec *decoding.Decoder) error {
 	// Get account and owner addresses from user
 	fmt.Println("\nEnter account addresses to monitor (comma-separated, or press Enter to skip):")
 	accountInput := utils.Prompt("")
@@ -192,7 +194,7 @@ func SubscribeToAccountUpdates(ctx context.Context, client pb.EventPublisherClie
 			if accountUpdate == nil {
 				continue
 			}
-			printer.PrintAccountUpdate(accountUpdate)
+			printer.PrintAccountUpdate(accountUpdate, dec)
 		default:
 			continue
 		}
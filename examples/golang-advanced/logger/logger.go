@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/mr-tron/base58"
+	"github.com/thorlabsDev/ThorStreamer/sdks/go/decoder"
 )
 
 // SignatureLogger handles signature logging
@@ -42,19 +43,22 @@ type LogEntry struct {
 	Slot      uint64 `json:"slot"`
 	ProgramID string `json:"program_id"`
 	Success   bool   `json:"success"`
+	// Instructions are the instructions decoded with an IDL, if any
+	Instructions []*decoder.DecodedInstruction `json:"instructions,omitempty"`
 }
 
-// LogSignature logs a transaction signature
-func (sl *SignatureLogger) LogSignature(signature []byte, slot uint64, programID string, success bool) error {
+// LogSignature logs a transaction signature with its decoded instructions
+func (sl *SignatureLogger) LogSignature(signature []byte, slot uint64, programID string, success bool, instructions []*decoder.DecodedInstruction) error {
 	sl.mu.Lock()
 	defer sl.mu.Unlock()
 
 	entry := LogEntry{
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		Signature: base58.Encode(signature),
-		Slot:      slot,
-		ProgramID: programID,
-		Success:   success,
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+		Signature:    base58.Encode(signature),
+		Slot:         slot,
+		ProgramID:    programID,
+		Success:      success,
+		Instructions: instructions,
 	}
 
 	data, err := json.Marshal(entry)
@@ -1,10 +1,15 @@
 package printer
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/mr-tron/base58"
+	"github.com/thorlabsDev/ThorStreamer/sdks/go/decoder"
 
+	"example/decoding"
 	pb "example/proto"
 	"example/utils"
 )
@@ -13,8 +18,9 @@ const (
 	lamportsPerSol = 1000000000 // SOL to lamports conversion rate
 )
 
-// PrintTransaction prints basic transaction information
-func PrintTransaction(tx *pb.TransactionEvent) {
+// PrintTransaction prints basic transaction information and the
+// instructions decoded with an IDL
+func PrintTransaction(tx *pb.TransactionEvent, decoded []*decoder.DecodedInstruction) {
 	fmt.Printf("\n📜 Transaction: %s\n", base58.Encode(tx.Signature))
 	fmt.Printf("├─ Slot: %d\n", tx.Slot)
 	if tx.Transaction != nil {
@@ -23,6 +29,13 @@ func PrintTransaction(tx *pb.TransactionEvent) {
 	}
 	fmt.Printf("├─ Success: %v\n", !tx.TransactionStatusMeta.IsStatusErr)
 
+	if len(decoded) > 0 {
+		fmt.Println("├─ Decoded Instructions:")
+		for _, dix := range decoded {
+			printDecodedInstruction("│  ", dix)
+		}
+	}
+
 	if len(tx.TransactionStatusMeta.LogMessages) > 0 {
 		fmt.Println("├─ Log Messages:")
 		for _, msg := range tx.TransactionStatusMeta.LogMessages {
@@ -32,28 +45,32 @@ func PrintTransaction(tx *pb.TransactionEvent) {
 	fmt.Println("└─ End Transaction\n")
 }
 
-// PrintDetailedTransaction prints detailed transaction information
-func PrintDetailedTransaction(tx *pb.TransactionEvent) {
+// PrintDetailedTransaction prints detailed transaction information, decoding
+// instructions of programs dec has an IDL for
+func PrintDetailedTransaction(tx *pb.TransactionEvent, dec *decoding.Decoder) {
 	fmt.Println("\n👛 Wallet Transaction Details:")
 	fmt.Printf("├─ Signature: %s\n", base58.Encode(tx.Signature))
 	fmt.Printf("├─ Slot: %d\n", tx.Slot)
 	fmt.Printf("├─ Is Vote Transaction: %v\n", tx.IsVote)
 
+	var keys []string
 	if tx.Transaction != nil {
 		fmt.Printf("├─ Transaction Version: %s\n",
 			utils.GetTransactionVersionString(tx.Transaction.Message.Version))
-		printTransactionDetails(tx.Transaction)
+		keys = utils.AccountKeys(tx.Transaction.Message)
+		printTransactionDetails(tx.Transaction, keys, dec)
 	}
 
 	if tx.TransactionStatusMeta != nil {
-		printTransactionStatusMeta(tx.TransactionStatusMeta)
+		printTransactionStatusMeta(tx.TransactionStatusMeta, keys, dec)
 	}
 
 	fmt.Println("└─ End Transaction\n")
 }
 
-// PrintAccountUpdate prints account update information (updated for new proto)
-func PrintAccountUpdate(account *pb.SubscribeUpdateAccountInfo) {
+// PrintAccountUpdate prints account update information (updated for new proto),
+// with the decoded data when dec knows the owner's layout
+func PrintAccountUpdate(account *pb.SubscribeUpdateAccountInfo, dec *decoding.Decoder) {
 	fmt.Println("\n💳 Account Update:")
 	fmt.Printf("├─ Address: %s\n", base58.Encode(account.Pubkey))
 	fmt.Printf("├─ Owner: %s\n", base58.Encode(account.Owner))
@@ -62,6 +79,13 @@ func PrintAccountUpdate(account *pb.SubscribeUpdateAccountInfo) {
 	fmt.Printf("├─ Rent Epoch: %v\n", account.RentEpoch)
 	fmt.Printf("├─ Write Version: %v\n", account.WriteVersion)
 
+	if name, fields, ok := dec.Account(account); ok {
+		fmt.Printf("├─ Decoded %s:\n", name)
+		for _, key := range sortedKeys(fields) {
+			fmt.Printf("│  ├─ %s: %s\n", key, formatValue(fields[key]))
+		}
+	}
+
 	if account.TxnSignature != nil {
 		fmt.Printf("├─ Transaction Signature: %s\n", base58.Encode(account.TxnSignature))
 	}
@@ -113,17 +137,17 @@ func PrintSlotStatus(slot *pb.SlotStatusEvent) {
 
 // Private helper functions for detailed printing
 
-func printTransactionDetails(tx *pb.SanitizedTransaction) {
+func printTransactionDetails(tx *pb.SanitizedTransaction, keys []string, dec *decoding.Decoder) {
 	fmt.Println("├─ Transaction Details:")
 	fmt.Printf("│  ├─ Message Hash: %s\n", base58.Encode(tx.MessageHash))
 	fmt.Printf("│  └─ Is Simple Vote: %v\n", tx.IsSimpleVoteTransaction)
 
 	if tx.Message != nil {
-		printMessage(tx.Message)
+		printMessage(tx.Message, keys, dec)
 	}
 }
 
-func printMessage(msg *pb.Message) {
+func printMessage(msg *pb.Message, keys []string, dec *decoding.Decoder) {
 	if msg == nil {
 		return
 	}
@@ -143,7 +167,7 @@ func printMessage(msg *pb.Message) {
 		printAddressTableLookups("│  ├─", msg.AddressTableLookups)
 	}
 
-	printInstructions("│  └─", msg.Instructions)
+	printInstructions("│  └─", msg.Instructions, keys, dec)
 }
 
 func printMessageHeader(header *pb.MessageHeader) {
@@ -200,24 +224,40 @@ func printAddressTableLookups(prefix string, lookups []*pb.MessageAddressTableLo
 	}
 }
 
-func printInstructions(prefix string, instructions []*pb.CompiledInstruction) {
+func printInstructions(prefix string, instructions []*pb.CompiledInstruction, keys []string, dec *decoding.Decoder) {
 	fmt.Printf("%s Instructions: %d\n", prefix, len(instructions))
 	for i, ix := range instructions {
 		fmt.Printf("│     ├─ Instruction %d:\n", i)
 		fmt.Printf("│     │  ├─ Program ID Index: %d\n", ix.ProgramIdIndex)
 		fmt.Printf("│     │  ├─ Account Indexes: %v\n", ix.Accounts)
+		if dix := dec.Instruction(keys, ix); dix != nil {
+			fmt.Printf("│     │  ├─ Data: %s\n", base58.Encode(ix.Data))
+			printDecodedInstruction("│     │  ", dix)
+			continue
+		}
 		fmt.Printf("│     │  └─ Data: %s\n", base58.Encode(ix.Data))
 	}
 }
 
-func printTransactionStatusMeta(meta *pb.TransactionStatusMeta) {
+func printDecodedInstruction(indent string, dix *decoder.DecodedInstruction) {
+	fmt.Printf("%s├─ Decoded: %s.%s\n", indent, dix.Program, dix.Name)
+	for _, key := range sortedKeys(dix.Args) {
+		fmt.Printf("%s│  ├─ %s: %s\n", indent, key, formatValue(dix.Args[key]))
+	}
+	fmt.Printf("%s└─ Accounts:\n", indent)
+	for _, acc := range dix.Accounts {
+		fmt.Printf("%s   ├─ %s: %s\n", indent, acc.Name, acc.Pubkey)
+	}
+}
+
+func printTransactionStatusMeta(meta *pb.TransactionStatusMeta, keys []string, dec *decoding.Decoder) {
 	fmt.Println("├─ Status Metadata:")
 	fmt.Printf("│  ├─ Status: %s\n", utils.FormatStatus(meta.IsStatusErr, meta.ErrorInfo))
 	fmt.Printf("│  ├─ Fee: %s SOL\n", utils.LamportsToSol(meta.Fee))
 
 	printBalanceChanges(meta)
 	printTokenBalances(meta)
-	printInnerInstructions(meta.InnerInstructions)
+	printInnerInstructions(meta.InnerInstructions, keys, dec)
 	printLogMessages(meta.LogMessages)
 	printRewards(meta.Rewards)
 }
@@ -271,7 +311,7 @@ func printTokenBalance(prefix string, balance *pb.TransactionTokenBalance) {
 	fmt.Printf("│  │  │  └─ Decimals: %d\n", balance.UiTokenAmount.Decimals)
 }
 
-func printInnerInstructions(instructions []*pb.InnerInstructions) {
+func printInnerInstructions(instructions []*pb.InnerInstructions, keys []string, dec *decoding.Decoder) {
 	if len(instructions) == 0 {
 		return
 	}
@@ -285,6 +325,9 @@ func printInnerInstructions(instructions []*pb.InnerInstructions) {
 				fmt.Printf("│  │  │  │  ├─ Program ID Index: %d\n", ix.Instruction.ProgramIdIndex)
 				fmt.Printf("│  │  │  │  ├─ Account Indexes: %v\n", ix.Instruction.Accounts)
 				fmt.Printf("│  │  │  │  └─ Data: %s\n", base58.Encode(ix.Instruction.Data))
+				if dix := dec.Instruction(keys, ix.Instruction); dix != nil {
+					printDecodedInstruction("│  │  │  │     ", dix)
+				}
 			}
 			if ix.StackHeight != nil {
 				fmt.Printf("│  │  │  │  └─ Stack Height: %d\n", *ix.StackHeight)
@@ -319,6 +362,24 @@ func printRewards(rewards []*pb.Reward) {
 	}
 }
 
+// formatValue formats a decoded value as compact JSON
+func formatValue(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return strings.Trim(string(data), `"`)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func lamportsToSol(lamports uint64) string {
 	return fmt.Sprintf("%.9f", float64(lamports)/float64(lamportsPerSol))
 }
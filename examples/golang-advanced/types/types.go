@@ -10,12 +10,14 @@ type Config struct {
 	ServerAddress     string   `json:"server_address"`
 	AuthToken         string   `json:"auth_token"`
 	ProgramFilters    []string `json:"program_filters"`
+	FilterMode        string   `json:"filter_mode"`
 	LogDirectory      string   `json:"log_directory"`
 	IncludeVote       bool     `json:"include_vote_transactions"`
 	IncludeFailed     bool     `json:"include_failed_transactions"`
 	MaxRetries        int      `json:"max_retries"`
 	SignatureLogFile  string   `json:"signature_log_file"`
 	ChannelBufferSize int      `json:"channel_buffer_size"`
+	IDLDirectory      string   `json:"idl_directory"`
 }
 
 // Filter handles program filtering logic
@@ -6,6 +6,9 @@ import (
 	"os"
 	"strings"
 
+	"github.com/mr-tron/base58"
+
+	pb "example/proto"
 	"example/types"
 )
 
@@ -80,3 +83,26 @@ func FormatStatus(isErr bool, errorInfo string) string {
 	}
 	return "Success"
 }
+
+// AccountKeys returns a message's account keys in base58, followed by the
+// writable and readonly addresses loaded from lookup tables, in the order
+// instruction account indexes refer to them
+func AccountKeys(msg *pb.Message) []string {
+	if msg == nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(msg.AccountKeys))
+	for _, key := range msg.AccountKeys {
+		keys = append(keys, base58.Encode(key))
+	}
+	if msg.LoadedAddresses != nil {
+		for _, key := range msg.LoadedAddresses.Writable {
+			keys = append(keys, base58.Encode(key))
+		}
+		for _, key := range msg.LoadedAddresses.Readonly {
+			keys = append(keys, base58.Encode(key))
+		}
+	}
+	return keys
+}
@@ -0,0 +1,59 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// TokenCredentials sends an authorization token with every call. The token
+// can be rotated at runtime; new subscriptions and reconnects use the latest
+// value.
+type TokenCredentials struct {
+	mu    sync.RWMutex
+	token string
+}
+
+var _ credentials.PerRPCCredentials = (*TokenCredentials)(nil)
+
+// NewTokenCredentials creates credentials sending the given token
+func NewTokenCredentials(token string) *TokenCredentials {
+	return &TokenCredentials{token: token}
+}
+
+// SetToken replaces the token sent with subsequent calls
+func (t *TokenCredentials) SetToken(token string) {
+	t.mu.Lock()
+	t.token = token
+	t.mu.Unlock()
+}
+
+// GetRequestMetadata implements credentials.PerRPCCredentials
+func (t *TokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.token == "" {
+		return nil, nil
+	}
+	return map[string]string{"authorization": t.token}, nil
+}
+
+// RequireTransportSecurity implements credentials.PerRPCCredentials. Tokens
+// are allowed over plaintext connections, matching the server deployment.
+func (t *TokenCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// SetToken rotates the token used by the client. It fails if the client was
+// created with custom Config.Credentials.
+func (c *Client) SetToken(token string) error {
+	tc, ok := c.creds.(*TokenCredentials)
+	if !ok {
+		return errors.New("client uses custom credentials")
+	}
+	tc.SetToken(token)
+	return nil
+}
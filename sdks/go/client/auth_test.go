@@ -0,0 +1,171 @@
+package thorclient
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mr-tron/base58"
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// testWallet returns a valid base58 address made of the byte b
+func testWallet(b byte) string {
+	return base58.Encode(bytes.Repeat([]byte{b}, 32))
+}
+
+// authRecorder is a server interceptor recording the authorization sent with
+// each stream, by method
+type authRecorder struct {
+	mu     sync.Mutex
+	tokens map[string][]string
+}
+
+func (r *authRecorder) intercept(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	md, _ := metadata.FromIncomingContext(ss.Context())
+	r.mu.Lock()
+	if r.tokens == nil {
+		r.tokens = make(map[string][]string)
+	}
+	r.tokens[info.FullMethod] = append(r.tokens[info.FullMethod], md.Get("authorization")...)
+	if len(md.Get("authorization")) == 0 {
+		r.tokens[info.FullMethod] = append(r.tokens[info.FullMethod], "")
+	}
+	r.mu.Unlock()
+	return handler(srv, ss)
+}
+
+func (r *authRecorder) get(method string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.tokens[method])
+}
+
+// staticCredentials sends a fixed authorization value
+type staticCredentials string
+
+func (c staticCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": string(c)}, nil
+}
+
+func (c staticCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+func TestAuthorization(t *testing.T) {
+	methods := []string{
+		pb.EventPublisher_SubscribeToTransactions_FullMethodName,
+		pb.EventPublisher_SubscribeToSlotStatus_FullMethodName,
+		pb.EventPublisher_SubscribeToWalletTransactions_FullMethodName,
+		pb.EventPublisher_SubscribeToAccountUpdates_FullMethodName,
+		pb.ThorStreamer_StreamUpdates_FullMethodName,
+	}
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		want        string // authorization sent, empty for none
+		setTokenErr bool
+	}{
+		{name: "token", cfg: Config{Token: "secret"}, want: "secret"},
+		{name: "no token", cfg: Config{}},
+		{name: "custom credentials", cfg: Config{Credentials: staticCredentials("custom")}, want: "custom", setTokenErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var recorder authRecorder
+			addr, _ := startPublisher(t, "", func(ctx context.Context, _ any, _ func(*pb.MessageWrapper) error) error {
+				return idle(ctx)
+			}, grpc.StreamInterceptor(recorder.intercept))
+			cfg := tt.cfg
+			cfg.ServerAddr = addr
+			c, err := NewClient(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if _, err := c.SubscribeToTransactions(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.SubscribeToSlotStatus(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.SubscribeToWalletTransactions(ctx, []string{testWallet(1)}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.SubscribeToAccountUpdates(ctx, []string{testWallet(2)}, nil); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := c.SubscribeToThorUpdates(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, method := range methods {
+				eventually(t, method, func() bool { return len(recorder.get(method)) > 0 })
+				if got := recorder.get(method); !slices.Equal(got, []string{tt.want}) {
+					t.Errorf("%s sent authorization %q, want %q", method, got, tt.want)
+				}
+			}
+			if err := c.SetToken("rotated"); (err != nil) != tt.setTokenErr {
+				t.Errorf("SetToken() = %v, want error %v", err, tt.setTokenErr)
+			}
+		})
+	}
+}
+
+func TestSetTokenOnReconnect(t *testing.T) {
+	var recorder authRecorder
+	rotated := make(chan struct{})
+	var mu sync.Mutex
+	calls := 0
+	addr, _ := startPublisher(t, "", func(ctx context.Context, _ any, send func(*pb.MessageWrapper) error) error {
+		mu.Lock()
+		calls++
+		first := calls == 1
+		mu.Unlock()
+		if first {
+			<-rotated
+			return status.Error(codes.Unavailable, "connection reset")
+		}
+		if err := send(txMessage(1, []byte{1})); err != nil {
+			return err
+		}
+		return idle(ctx)
+	}, grpc.StreamInterceptor(recorder.intercept))
+
+	c, err := NewClient(Config{ServerAddr: addr, Token: "old", InitialBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s, err := c.SubscribeToTransactions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	method := pb.EventPublisher_SubscribeToTransactions_FullMethodName
+	eventually(t, "the first stream", func() bool { return len(recorder.get(method)) == 1 })
+	if err := c.SetToken("new"); err != nil {
+		t.Fatal(err)
+	}
+	close(rotated)
+
+	// The reconnect sends the rotated token
+	if _, err := s.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	if got := recorder.get(method); !slices.Equal(got, []string{"old", "new"}) {
+		t.Errorf("authorization sent %q, want [old new]", got)
+	}
+}
@@ -0,0 +1,286 @@
+package thorclient
+
+import (
+	"cmp"
+	"context"
+	"encoding/binary"
+	"errors"
+	"iter"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+const (
+	defaultBlockDelay   = 100 * time.Millisecond
+	defaultBlockTimeout = 10 * time.Second
+)
+
+// ErrBlockEmitted means a transaction arrived after its slot's block was
+// delivered
+var ErrBlockEmitted = errors.New("block was already emitted")
+
+// Block is the transactions of one slot in index order, with the slot's
+// details as reported by its status
+type Block struct {
+	Slot         uint64
+	Parent       uint64
+	BlockHash    []byte
+	BlockHeight  uint64
+	Transactions []*pb.TransactionEvent
+	// Complete is false for blocks emitted on timeout before their slot was
+	// reported complete and processed; they may lack transactions and
+	// details
+	Complete bool
+}
+
+// BlockOptions configures AssembleBlocks
+type BlockOptions struct {
+	// Delay is how long to keep collecting after a slot is complete and
+	// processed, for transactions still in flight on the transaction
+	// stream, which is not ordered with the slot stream. Zero means 100ms;
+	// negative emits at once.
+	Delay time.Duration
+	// Timeout emits a block this long after its first transaction even if
+	// the slot was not complete and processed yet. Zero means 10s; negative
+	// waits for the slot status.
+	Timeout time.Duration
+	// OnDropped is called with transactions that are not delivered: err is
+	// ErrSlotSkipped for slots that died or were skipped before being
+	// processed, ErrSlotUntracked for slots too old for the tracker, or
+	// ErrBlockEmitted for transactions arriving after their block
+	OnDropped func(slot uint64, txs []*pb.TransactionEvent, err error)
+}
+
+// BlockAssembler groups a transaction stream into blocks, emitting each once
+// a SlotTracker reports its slot complete, with all shreds received, and
+// processed. Slots without transactions produce no block.
+type BlockAssembler struct {
+	ctx     context.Context
+	tracker *SlotTracker
+	opts    BlockOptions
+	closers []func()
+
+	txs chan txResult
+	// mu guards pending, which only Recv modifies, against Pending
+	mu      sync.Mutex
+	pending map[uint64]*pendingBlock
+	emitted *recentSet
+	ready   []*Block
+}
+
+type pendingBlock struct {
+	txs   []*pb.TransactionEvent
+	first time.Time
+	// settled is when the slot was first seen complete and processed
+	settled time.Time
+}
+
+// AssembleBlocks groups the transactions of txs per slot and emits a Block
+// when tracker reports the slot complete and processed, or after the
+// timeout. It reads
+// txs until ctx is done or the stream fails.
+func AssembleBlocks(ctx context.Context, txs Receiver[*pb.TransactionEvent], tracker *SlotTracker, opts BlockOptions) *BlockAssembler {
+	if opts.Delay == 0 {
+		opts.Delay = defaultBlockDelay
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = defaultBlockTimeout
+	}
+	a := &BlockAssembler{
+		ctx:     ctx,
+		tracker: tracker,
+		opts:    opts,
+		txs:     make(chan txResult, 256),
+		pending: make(map[uint64]*pendingBlock),
+		emitted: newRecentSet(slotHistory),
+	}
+	go a.pump(txs)
+	return a
+}
+
+// SubscribeToBlocks subscribes to transactions and slot statuses and
+// assembles them into blocks. It uses two subscriptions.
+func (c *Client) SubscribeToBlocks(ctx context.Context, opts BlockOptions) (*BlockAssembler, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	slots, err := c.SubscribeToSlotStatus(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	txs, err := c.SubscribeToTransactions(ctx)
+	if err != nil {
+		slots.Close()
+		cancel()
+		return nil, err
+	}
+
+	a := AssembleBlocks(ctx, txs, TrackSlots(ctx, slots), opts)
+	a.closers = []func(){cancel, txs.Close, slots.Close}
+	return a, nil
+}
+
+// Recv blocks until the next block is assembled
+func (a *BlockAssembler) Recv() (*Block, error) {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		if len(a.ready) > 0 {
+			block := a.ready[0]
+			a.ready[0] = nil
+			a.ready = a.ready[1:]
+			return block, nil
+		}
+
+		changed := a.tracker.changes()
+		next, err := a.settle(time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if len(a.ready) > 0 {
+			continue
+		}
+
+		var wake <-chan time.Time
+		if !next.IsZero() {
+			timer.Reset(time.Until(next))
+			wake = timer.C
+		}
+		select {
+		case r := <-a.txs:
+			if r.err != nil {
+				return nil, r.err
+			}
+			a.add(r.tx)
+		case <-changed:
+		case <-wake:
+		case <-a.ctx.Done():
+			return nil, a.ctx.Err()
+		}
+		timer.Stop()
+	}
+}
+
+// All returns an iterator over the assembled blocks, see Stream.All
+func (a *BlockAssembler) All() iter.Seq2[*Block, error] {
+	return all(a)
+}
+
+// Close ends the subscriptions opened by SubscribeToBlocks. Streams passed to
+// AssembleBlocks are left to the caller.
+func (a *BlockAssembler) Close() {
+	for _, closer := range a.closers {
+		closer()
+	}
+}
+
+// Pending returns the number of slots with transactions waiting to be emitted
+func (a *BlockAssembler) Pending() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.pending)
+}
+
+func (a *BlockAssembler) add(tx *pb.TransactionEvent) {
+	if a.emitted.contains(slotKey(tx.Slot)) {
+		if a.opts.OnDropped != nil {
+			a.opts.OnDropped(tx.Slot, []*pb.TransactionEvent{tx}, ErrBlockEmitted)
+		}
+		return
+	}
+	block := a.pending[tx.Slot]
+	if block == nil {
+		block = &pendingBlock{first: time.Now()}
+		a.mu.Lock()
+		a.pending[tx.Slot] = block
+		a.mu.Unlock()
+	}
+	block.txs = append(block.txs, tx)
+}
+
+// settle emits or drops the pending slots that are due at now and returns
+// when the next one falls due, or the zero time if none waits on a timer
+func (a *BlockAssembler) settle(now time.Time) (time.Time, error) {
+	var next time.Time
+	due := func(t time.Time) bool {
+		if !t.After(now) {
+			return true
+		}
+		if next.IsZero() || t.Before(next) {
+			next = t
+		}
+		return false
+	}
+
+	for _, slot := range slices.Sorted(maps.Keys(a.pending)) {
+		block := a.pending[slot]
+		err, ok := a.tracker.outcome(slot, CommitmentProcessed)
+		switch {
+		case errors.Is(err, ErrSlotSkipped), errors.Is(err, ErrSlotUntracked):
+			a.drop(slot, err)
+		case err != nil:
+			// The tracker stopped and can no longer decide
+			return time.Time{}, err
+		case ok && a.tracker.completedSlot(slot):
+			if block.settled.IsZero() {
+				block.settled = now
+			}
+			if a.opts.Delay < 0 || due(block.settled.Add(a.opts.Delay)) {
+				a.emit(slot, true)
+			}
+		case a.opts.Timeout > 0 && due(block.first.Add(a.opts.Timeout)):
+			a.emit(slot, false)
+		}
+	}
+	return next, nil
+}
+
+func (a *BlockAssembler) emit(slot uint64, complete bool) {
+	block := a.pending[slot]
+	a.mu.Lock()
+	delete(a.pending, slot)
+	a.mu.Unlock()
+	a.emitted.add(slotKey(slot))
+
+	slices.SortFunc(block.txs, func(x, y *pb.TransactionEvent) int {
+		return cmp.Compare(x.Index, y.Index)
+	})
+	out := &Block{Slot: slot, Transactions: block.txs, Complete: complete}
+	if info, ok := a.tracker.Slot(slot); ok {
+		out.Parent, out.BlockHash, out.BlockHeight = info.Parent, info.BlockHash, info.BlockHeight
+	}
+	a.ready = append(a.ready, out)
+}
+
+func (a *BlockAssembler) drop(slot uint64, err error) {
+	block := a.pending[slot]
+	a.mu.Lock()
+	delete(a.pending, slot)
+	a.mu.Unlock()
+	if a.opts.OnDropped != nil {
+		a.opts.OnDropped(slot, block.txs, err)
+	}
+}
+
+// slotKey identifies a slot in a recentSet
+func slotKey(slot uint64) string {
+	return string(binary.BigEndian.AppendUint64(nil, slot))
+}
+
+func (a *BlockAssembler) pump(txs Receiver[*pb.TransactionEvent]) {
+	for {
+		tx, err := txs.Recv()
+		select {
+		case a.txs <- txResult{tx: tx, err: err}:
+		case <-a.ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
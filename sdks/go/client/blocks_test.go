@@ -0,0 +1,185 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+func TestAssembleBlocks(t *testing.T) {
+	const (
+		processed = int32(CommitmentProcessed)
+		rooted    = int32(CommitmentRooted)
+		completed = slotStatusCompleted
+		dead      = slotStatusDead
+	)
+	type tx struct{ slot, index uint64 }
+	type block struct {
+		slot     uint64
+		parent   uint64
+		indexes  []uint64
+		complete bool
+	}
+
+	tests := []struct {
+		name    string
+		opts    BlockOptions
+		txs     []tx
+		events  []slotUpdate
+		want    []block
+		dropped map[uint64]error
+		pending int
+	}{
+		{
+			name:   "ordered by index",
+			opts:   BlockOptions{Delay: -1},
+			txs:    []tx{{100, 3}, {100, 1}, {100, 2}},
+			events: []slotUpdate{{100, 99, completed}, {100, 99, processed}},
+			want:   []block{{100, 99, []uint64{1, 2, 3}, true}},
+		},
+		{
+			name:   "slots emitted in order",
+			opts:   BlockOptions{Delay: -1},
+			txs:    []tx{{101, 0}, {100, 0}},
+			events: []slotUpdate{{101, 100, completed}, {100, 99, completed}, {100, 99, processed}, {101, 100, processed}},
+			want:   []block{{100, 99, []uint64{0}, true}, {101, 100, []uint64{0}, true}},
+		},
+		{
+			name:   "processed before completion",
+			opts:   BlockOptions{Delay: -1},
+			txs:    []tx{{100, 0}},
+			events: []slotUpdate{{100, 99, processed}, {100, 99, completed}},
+			want:   []block{{100, 99, []uint64{0}, true}},
+		},
+		{
+			name:    "completion waits for processed",
+			opts:    BlockOptions{Delay: -1, Timeout: -1},
+			txs:     []tx{{100, 0}},
+			events:  []slotUpdate{{100, 99, completed}},
+			pending: 1,
+		},
+		{
+			name:    "processed waits for completion",
+			opts:    BlockOptions{Delay: -1, Timeout: -1},
+			txs:     []tx{{100, 0}},
+			events:  []slotUpdate{{100, 99, processed}},
+			pending: 1,
+		},
+		{
+			name:   "timeout without completion",
+			opts:   BlockOptions{Delay: -1, Timeout: 20 * time.Millisecond},
+			txs:    []tx{{100, 1}, {100, 0}},
+			events: []slotUpdate{{100, 99, processed}},
+			want:   []block{{100, 99, []uint64{0, 1}, false}},
+		},
+		{
+			name: "timeout without status",
+			opts: BlockOptions{Delay: -1, Timeout: 20 * time.Millisecond},
+			txs:  []tx{{100, 0}},
+			want: []block{{100, 0, []uint64{0}, false}},
+		},
+		{
+			name:    "dead slot dropped",
+			opts:    BlockOptions{Delay: -1},
+			txs:     []tx{{101, 0}, {101, 1}},
+			events:  []slotUpdate{{101, 0, dead}},
+			dropped: map[uint64]error{101: ErrSlotSkipped},
+		},
+		{
+			name:    "skipped slot dropped",
+			opts:    BlockOptions{Delay: -1},
+			txs:     []tx{{101, 0}, {102, 0}},
+			events:  []slotUpdate{{100, 99, rooted}, {102, 100, completed}, {102, 100, rooted}},
+			want:    []block{{102, 100, []uint64{0}, true}},
+			dropped: map[uint64]error{101: ErrSlotSkipped},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			tracker, _ := newTestTracker(t)
+			txs := make(chan *pb.TransactionEvent, len(tt.txs))
+			var dropped droppedTxs
+			tt.opts.OnDropped = dropped.add
+			a := AssembleBlocks(ctx, chanReceiver[*pb.TransactionEvent]{ctx, txs}, tracker, tt.opts)
+			blocks := receive(a)
+
+			slots := make(map[uint64]bool)
+			for _, tx := range tt.txs {
+				txs <- &pb.TransactionEvent{Slot: tx.slot, Index: tx.index}
+				slots[tx.slot] = true
+			}
+			eventually(t, "transactions to be collected", func() bool { return a.Pending() == len(slots) })
+			for _, ev := range tt.events {
+				tracker.observe(ev.proto())
+			}
+
+			for _, want := range tt.want {
+				var got *Block
+				select {
+				case got = <-blocks:
+				case <-time.After(time.Second):
+					t.Fatalf("block %d was not emitted", want.slot)
+				}
+				var indexes []uint64
+				for _, tx := range got.Transactions {
+					indexes = append(indexes, tx.Index)
+				}
+				if got.Slot != want.slot || got.Parent != want.parent || got.Complete != want.complete || !slices.Equal(indexes, want.indexes) {
+					t.Errorf("block = {slot %d, parent %d, indexes %v, complete %v}, want %+v", got.Slot, got.Parent, indexes, got.Complete, want)
+				}
+			}
+			for slot, err := range tt.dropped {
+				eventually(t, "dropped transactions", func() bool { return dropped.err(slot) != nil })
+				if got := dropped.err(slot); !errors.Is(got, err) {
+					t.Errorf("slot %d dropped with %v, want %v", slot, got, err)
+				}
+			}
+			eventually(t, "pending slots to settle", func() bool { return a.Pending() == tt.pending })
+			select {
+			case got := <-blocks:
+				t.Errorf("unexpected block %d", got.Slot)
+			case <-time.After(50 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestAssembleBlocksLateTransaction(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tracker, _ := newTestTracker(t)
+	txs := make(chan *pb.TransactionEvent, 2)
+	var dropped droppedTxs
+	a := AssembleBlocks(ctx, chanReceiver[*pb.TransactionEvent]{ctx, txs}, tracker, BlockOptions{Delay: 20 * time.Millisecond, OnDropped: dropped.add})
+	blocks := receive(a)
+
+	tracker.observe(slotUpdate{100, 99, slotStatusCompleted}.proto())
+	tracker.observe(slotUpdate{100, 99, int32(CommitmentProcessed)}.proto())
+	txs <- &pb.TransactionEvent{Slot: 100, Index: 1}
+	// Still within the delay after the first transaction
+	txs <- &pb.TransactionEvent{Slot: 100, Index: 0}
+
+	select {
+	case block := <-blocks:
+		if len(block.Transactions) != 2 || !block.Complete {
+			t.Fatalf("block has %d transactions, complete %v, want 2 and complete", len(block.Transactions), block.Complete)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("block was not emitted")
+	}
+
+	txs <- &pb.TransactionEvent{Slot: 100, Index: 2}
+	eventually(t, "the late transaction to be dropped", func() bool { return dropped.len() == 1 })
+	if err := dropped.err(100); !errors.Is(err, ErrBlockEmitted) {
+		t.Errorf("late transaction dropped with %v, want ErrBlockEmitted", err)
+	}
+	if n := a.Pending(); n != 0 {
+		t.Errorf("Pending() = %d after a late transaction, want 0", n)
+	}
+}
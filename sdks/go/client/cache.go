@@ -0,0 +1,308 @@
+package thorclient
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mr-tron/base58"
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// AccountState is the latest known state of an account
+type AccountState struct {
+	Account *pb.SubscribeUpdateAccountInfo
+	// Slot and Commitment are those of the update that carried the state,
+	// with the commitment raised by the cache's SlotTracker if it has one
+	Slot       uint64
+	Commitment Commitment
+	Updated    time.Time
+}
+
+// AccountCacheOptions configures CacheAccounts
+type AccountCacheOptions struct {
+	// MaxAccounts bounds the cache, evicting the least recently updated or
+	// read account. Zero means unbounded.
+	MaxAccounts int
+	// Tracker, if set, keeps the commitment of cached states up to date
+	Tracker *SlotTracker
+	// OnDiff, if set, is called with the changes of each applied update
+	// against the cached state it replaces, one call at a time in the order
+	// the updates are applied. It must not call Apply.
+	OnDiff func(AccountDiff)
+	// Layouts decode account data by base58 owner program for the Fields of
+	// an AccountDiff
+	Layouts map[string]LayoutDecoder
+}
+
+// AccountCache keeps the latest state of every account seen on an account
+// update stream, the one with the highest write_version per pubkey
+type AccountCache struct {
+	opts AccountCacheOptions
+
+	// applyMu serializes Apply while OnDiff is set, so that diffs are
+	// delivered in write order without holding mu during the callback
+	applyMu sync.Mutex
+
+	mu       sync.Mutex
+	accounts map[string]*list.Element
+	lru      *list.List
+	owners   map[string]map[string]struct{}
+	watchers map[string]map[chan AccountState]struct{}
+	stopped  bool
+
+	done chan struct{}
+	err  error
+}
+
+// CacheAccounts starts an AccountCache fed by stream. It stops when the
+// stream ends or ctx is done, closing the Watch channels. Use AccountUpdates
+// to feed it from a ThorStream.
+func CacheAccounts(ctx context.Context, stream Receiver[*pb.SubscribeUpdateAccountInfo], opts AccountCacheOptions) *AccountCache {
+	c := &AccountCache{
+		opts:     opts,
+		accounts: make(map[string]*list.Element),
+		lru:      list.New(),
+		owners:   make(map[string]map[string]struct{}),
+		watchers: make(map[string]map[chan AccountState]struct{}),
+		done:     make(chan struct{}),
+	}
+	go func() {
+		c.stop(c.run(ctx, stream))
+	}()
+	return c
+}
+
+func (c *AccountCache) run(ctx context.Context, stream Receiver[*pb.SubscribeUpdateAccountInfo]) error {
+	for {
+		account, err := stream.Recv()
+		if err != nil {
+			if IsStreamDone(err) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		c.Apply(account)
+	}
+}
+
+func (c *AccountCache) stop(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = err
+	c.stopped = true
+	for _, watchers := range c.watchers {
+		for ch := range watchers {
+			close(ch)
+		}
+	}
+	clear(c.watchers)
+	close(c.done)
+}
+
+// Done is closed when the cache has stopped
+func (c *AccountCache) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the stream error that stopped the cache, or nil
+func (c *AccountCache) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Apply stores an account state unless one with the same or a higher
+// write_version is cached, reporting whether it did. The stream's updates are
+// applied this way; it can also seed the cache from an RPC snapshot.
+func (c *AccountCache) Apply(account *pb.SubscribeUpdateAccountInfo) bool {
+	if c.opts.OnDiff == nil {
+		_, applied := c.store(account)
+		return applied
+	}
+
+	c.applyMu.Lock()
+	defer c.applyMu.Unlock()
+	old, applied := c.store(account)
+	if applied && old != nil {
+		c.opts.OnDiff(DiffAccounts(old, account, c.opts.Layouts))
+	}
+	return applied
+}
+
+// store caches account, returning the state it replaced
+func (c *AccountCache) store(account *pb.SubscribeUpdateAccountInfo) (*pb.SubscribeUpdateAccountInfo, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := string(account.GetPubkey())
+	state := AccountState{
+		Account:    account,
+		Slot:       account.GetSlot().GetSlot(),
+		Commitment: Commitment(account.GetSlot().GetStatus()),
+		Updated:    time.Now(),
+	}
+	if state.Commitment < CommitmentProcessed || state.Commitment > CommitmentRooted {
+		state.Commitment = CommitmentProcessed
+	}
+
+	var prev *pb.SubscribeUpdateAccountInfo
+	if elem, ok := c.accounts[key]; ok {
+		cached := elem.Value.(*AccountState)
+		if account.GetWriteVersion() <= cached.Account.GetWriteVersion() {
+			return nil, false
+		}
+		prev = cached.Account
+		c.unindex(key, prev.GetOwner())
+		*cached = state
+		c.lru.MoveToFront(elem)
+	} else {
+		c.accounts[key] = c.lru.PushFront(&state)
+		c.evict()
+	}
+	c.index(key, account.GetOwner())
+
+	for ch := range c.watchers[key] {
+		// Replace a state the watcher has not read yet
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- c.current(state)
+	}
+	return prev, true
+}
+
+// Get returns the cached state of the account with base58 pubkey
+func (c *AccountCache) Get(pubkey string) (AccountState, bool) {
+	key, err := base58.Decode(pubkey)
+	if err != nil {
+		return AccountState{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.accounts[string(key)]
+	if !ok {
+		return AccountState{}, false
+	}
+	c.lru.MoveToFront(elem)
+	return c.current(*elem.Value.(*AccountState)), true
+}
+
+// ByOwner returns the cached states of the accounts owned by the base58
+// program or wallet owner
+func (c *AccountCache) ByOwner(owner string) []AccountState {
+	key, err := base58.Decode(owner)
+	if err != nil {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var states []AccountState
+	for pubkey := range c.owners[string(key)] {
+		states = append(states, c.current(*c.accounts[pubkey].Value.(*AccountState)))
+	}
+	return states
+}
+
+// Len returns the number of cached accounts
+func (c *AccountCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.accounts)
+}
+
+// Watch returns a channel receiving the states of the account with base58
+// pubkey as they are applied. The channel holds only the latest state not yet
+// read. It is closed by the returned cancel function or when the cache stops.
+func (c *AccountCache) Watch(pubkey string) (<-chan AccountState, func()) {
+	ch := make(chan AccountState, 1)
+	key, err := base58.Decode(pubkey)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil || c.stopped {
+		close(ch)
+		return ch, func() {}
+	}
+	watchers := c.watchers[string(key)]
+	if watchers == nil {
+		watchers = make(map[chan AccountState]struct{})
+		c.watchers[string(key)] = watchers
+	}
+	watchers[ch] = struct{}{}
+
+	return ch, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := watchers[ch]; ok {
+			delete(watchers, ch)
+			if len(watchers) == 0 {
+				delete(c.watchers, string(key))
+			}
+			close(ch)
+		}
+	}
+}
+
+// current raises the state's commitment to what the tracker knows
+func (c *AccountCache) current(state AccountState) AccountState {
+	if c.opts.Tracker != nil {
+		if commitment, ok := c.opts.Tracker.StatusOf(state.Slot); ok && commitment > state.Commitment {
+			state.Commitment = commitment
+		}
+	}
+	return state
+}
+
+// evict drops the least recently used accounts over MaxAccounts
+func (c *AccountCache) evict() {
+	for c.opts.MaxAccounts > 0 && c.lru.Len() > c.opts.MaxAccounts {
+		state := c.lru.Remove(c.lru.Back()).(*AccountState)
+		key := string(state.Account.GetPubkey())
+		delete(c.accounts, key)
+		c.unindex(key, state.Account.GetOwner())
+	}
+}
+
+func (c *AccountCache) index(key string, owner []byte) {
+	accounts := c.owners[string(owner)]
+	if accounts == nil {
+		accounts = make(map[string]struct{})
+		c.owners[string(owner)] = accounts
+	}
+	accounts[key] = struct{}{}
+}
+
+func (c *AccountCache) unindex(key string, owner []byte) {
+	accounts := c.owners[string(owner)]
+	delete(accounts, key)
+	if len(accounts) == 0 {
+		delete(c.owners, string(owner))
+	}
+}
+
+// AccountUpdates adapts a stream of MessageWrapper, such as a ThorStream, to
+// the account updates it carries
+func AccountUpdates(stream Receiver[*pb.MessageWrapper]) Receiver[*pb.SubscribeUpdateAccountInfo] {
+	return accountUpdates{stream}
+}
+
+type accountUpdates struct {
+	stream Receiver[*pb.MessageWrapper]
+}
+
+func (a accountUpdates) Recv() (*pb.SubscribeUpdateAccountInfo, error) {
+	for {
+		msg, err := a.stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		if account := msg.GetAccountUpdate(); account != nil {
+			return account, nil
+		}
+	}
+}
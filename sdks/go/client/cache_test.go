@@ -0,0 +1,226 @@
+package thorclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/mr-tron/base58"
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// cachedAccount is an update of the account testWallet(b) owned by
+// testWallet(owner)
+func cachedAccount(b, owner byte, writeVersion, slot uint64, status int32) *pb.SubscribeUpdateAccountInfo {
+	return &pb.SubscribeUpdateAccountInfo{
+		Pubkey:       bytes.Repeat([]byte{b}, 32),
+		Owner:        bytes.Repeat([]byte{owner}, 32),
+		WriteVersion: writeVersion,
+		Slot:         &pb.SlotStatus{Slot: slot, Status: status},
+	}
+}
+
+// newTestCache returns a cache fed only through Apply
+func newTestCache(t *testing.T, opts AccountCacheOptions) *AccountCache {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	return CacheAccounts(ctx, idleReceiver[*pb.SubscribeUpdateAccountInfo]{ctx}, opts)
+}
+
+func TestAccountCacheApply(t *testing.T) {
+	c := newTestCache(t, AccountCacheOptions{})
+	updates := []struct {
+		account *pb.SubscribeUpdateAccountInfo
+		applied bool
+	}{
+		{cachedAccount(1, 9, 5, 100, 0), true},
+		{cachedAccount(1, 9, 5, 100, 1), false}, // same write_version
+		{cachedAccount(1, 9, 4, 101, 0), false}, // older write_version
+		{cachedAccount(2, 9, 1, 100, 0), true},
+		{cachedAccount(1, 8, 6, 101, 5), true}, // new owner, created bank status
+	}
+	for i, u := range updates {
+		if applied := c.Apply(u.account); applied != u.applied {
+			t.Errorf("Apply %d = %v, want %v", i, applied, u.applied)
+		}
+	}
+
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+	state, ok := c.Get(testWallet(1))
+	if !ok || state.Account.WriteVersion != 6 || state.Slot != 101 || state.Updated.IsZero() {
+		t.Fatalf("Get() = %+v, %v, want write_version 6 at slot 101", state, ok)
+	}
+	if state.Commitment != CommitmentProcessed {
+		t.Errorf("commitment of a replay status = %v, want processed", state.Commitment)
+	}
+	if _, ok := c.Get(testWallet(3)); ok {
+		t.Error("Get() found an account never applied")
+	}
+	if _, ok := c.Get("not base58!"); ok {
+		t.Error("Get() found an invalid pubkey")
+	}
+
+	tests := []struct {
+		owner byte
+		want  []byte // first pubkey byte of the owned accounts
+	}{
+		{9, []byte{2}},
+		{8, []byte{1}},
+		{7, nil},
+	}
+	for _, tt := range tests {
+		var got []byte
+		for _, state := range c.ByOwner(testWallet(tt.owner)) {
+			got = append(got, state.Account.Pubkey[0])
+		}
+		slices.Sort(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ByOwner(%d) = accounts %v, want %v", tt.owner, got, tt.want)
+		}
+	}
+}
+
+func TestAccountCacheEviction(t *testing.T) {
+	c := newTestCache(t, AccountCacheOptions{MaxAccounts: 2})
+	c.Apply(cachedAccount(1, 9, 1, 100, 0))
+	c.Apply(cachedAccount(2, 9, 1, 100, 0))
+	// Reading account 1 makes account 2 the least recently used
+	c.Get(testWallet(1))
+	c.Apply(cachedAccount(3, 9, 1, 100, 0))
+
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+	for b, want := range map[byte]bool{1: true, 2: false, 3: true} {
+		if _, ok := c.Get(testWallet(b)); ok != want {
+			t.Errorf("account %d cached = %v, want %v", b, ok, want)
+		}
+	}
+	if owned := c.ByOwner(testWallet(9)); len(owned) != 2 {
+		t.Errorf("ByOwner() = %d accounts, want the 2 cached", len(owned))
+	}
+}
+
+func TestAccountCacheCommitment(t *testing.T) {
+	tracker, _ := newTestTracker(t)
+	c := newTestCache(t, AccountCacheOptions{Tracker: tracker})
+	c.Apply(cachedAccount(1, 9, 1, 100, 0))
+
+	if state, _ := c.Get(testWallet(1)); state.Commitment != CommitmentProcessed {
+		t.Errorf("commitment = %v, want processed", state.Commitment)
+	}
+	tracker.observe(slotUpdate{100, 99, int32(CommitmentConfirmed)}.proto())
+	if state, _ := c.Get(testWallet(1)); state.Commitment != CommitmentConfirmed {
+		t.Errorf("commitment after confirmation = %v, want confirmed", state.Commitment)
+	}
+}
+
+func TestAccountCacheWatch(t *testing.T) {
+	c := newTestCache(t, AccountCacheOptions{})
+	ch, cancel := c.Watch(testWallet(1))
+	other, _ := c.Watch(testWallet(1))
+
+	c.Apply(cachedAccount(1, 9, 1, 100, 0))
+	c.Apply(cachedAccount(2, 9, 1, 100, 0))
+	c.Apply(cachedAccount(1, 9, 2, 101, 0))
+	// Only the latest unread state is kept
+	for _, watcher := range []<-chan AccountState{ch, other} {
+		select {
+		case state := <-watcher:
+			if state.Account.WriteVersion != 2 {
+				t.Errorf("watched write_version %d, want 2", state.Account.WriteVersion)
+			}
+		default:
+			t.Fatal("no state delivered to a watcher")
+		}
+	}
+
+	cancel()
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Error("channel open after cancel")
+	}
+	c.Apply(cachedAccount(1, 9, 3, 102, 0))
+	if state := <-other; state.Account.WriteVersion != 3 {
+		t.Errorf("remaining watcher got write_version %d, want 3", state.Account.WriteVersion)
+	}
+
+	if invalid, _ := c.Watch("not base58!"); !isClosed(invalid) {
+		t.Error("Watch of an invalid pubkey returned an open channel")
+	}
+}
+
+func TestCacheAccounts(t *testing.T) {
+	errBroken := errors.New("broken stream")
+	tests := []struct {
+		name      string
+		streamErr error
+	}{
+		{name: "stream ends"},
+		{name: "stream fails", streamErr: errBroken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			messages := []*pb.MessageWrapper{
+				accountMessage(1, 1),
+				slotMessage(100, 0),
+				accountMessage(2, 1),
+				accountMessage(1, 2),
+			}
+			source := make(chan *pb.MessageWrapper, len(messages))
+			for _, msg := range messages {
+				source <- msg
+			}
+			var stream Receiver[*pb.MessageWrapper] = chanReceiver[*pb.MessageWrapper]{ctx, source}
+			if tt.streamErr != nil {
+				stream = failingReceiver[*pb.MessageWrapper]{stream, tt.streamErr}
+			}
+
+			c := CacheAccounts(ctx, AccountUpdates(stream), AccountCacheOptions{})
+			var pubkey [32]byte
+			pubkey[0] = 1
+			watch, _ := c.Watch(base58.Encode(pubkey[:]))
+			close(source)
+
+			select {
+			case <-c.Done():
+			case <-time.After(time.Second):
+				t.Fatal("cache did not stop at the end of the stream")
+			}
+			if !errors.Is(c.Err(), tt.streamErr) || (c.Err() == nil) != (tt.streamErr == nil) {
+				t.Errorf("Err() = %v, want %v", c.Err(), tt.streamErr)
+			}
+			if c.Len() != 2 {
+				t.Errorf("Len() = %d, want 2", c.Len())
+			}
+			if !isClosed(watch) {
+				t.Error("Watch channel open after the cache stopped")
+			}
+			if late, _ := c.Watch(base58.Encode(pubkey[:])); !isClosed(late) {
+				t.Error("Watch after the cache stopped returned an open channel")
+			}
+		})
+	}
+}
+
+// isClosed drains ch and reports whether it is closed
+func isClosed[T any](ch <-chan T) bool {
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				return true
+			}
+		default:
+			return false
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package thorclient
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// CheckpointStore persists the last fully observed slot of each subscription,
+// so that gaps are also detected across restarts. Implementations must be
+// safe for concurrent use.
+type CheckpointStore interface {
+	// Load returns the slot saved under key, or ok false if there is none
+	Load(key string) (slot uint64, ok bool, err error)
+	// Save records slot under key
+	Save(key string, slot uint64) error
+}
+
+// Gap is an inclusive range of slots for which a subscription may have missed
+// messages, e.g. while it was reconnecting. For sparse streams such as wallet
+// subscriptions the range is an upper bound.
+type Gap struct {
+	Stream SubscriptionType
+	// Key identifies the subscription in the CheckpointStore
+	Key      string
+	FromSlot uint64
+	ToSlot   uint64
+}
+
+// MemoryCheckpointStore keeps checkpoints for the lifetime of the process
+type MemoryCheckpointStore struct {
+	mu    sync.Mutex
+	slots map[string]uint64
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{slots: make(map[string]uint64)}
+}
+
+func (s *MemoryCheckpointStore) Load(key string) (uint64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slot, ok := s.slots[key]
+	return slot, ok, nil
+}
+
+func (s *MemoryCheckpointStore) Save(key string, slot uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.slots[key] = slot
+	return nil
+}
+
+// checkpointFlushInterval is how long a FileCheckpointStore collects saves
+// before writing them
+const checkpointFlushInterval = time.Second
+
+// FileCheckpointStore keeps checkpoints in a JSON file that is replaced
+// atomically. Saves are written in the background at most once per second;
+// Flush, which Client.Close calls, writes them at once.
+type FileCheckpointStore struct {
+	path string
+
+	// writeMu orders the file writes so that an older snapshot never
+	// replaces a newer one
+	writeMu sync.Mutex
+
+	mu        sync.Mutex
+	slots     map[string]uint64
+	scheduled bool
+	err       error // of the last background write, returned by the next Save
+}
+
+// NewFileCheckpointStore opens the checkpoints saved at path. A missing file
+// starts an empty store.
+func NewFileCheckpointStore(path string) (*FileCheckpointStore, error) {
+	s := &FileCheckpointStore{path: path, slots: make(map[string]uint64)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoints: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.slots); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoints %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *FileCheckpointStore) Load(key string) (uint64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slot, ok := s.slots[key]
+	return slot, ok, nil
+}
+
+// Save records slot under key and schedules a write. It returns the error of
+// the previous background write, if any.
+func (s *FileCheckpointStore) Save(key string, slot uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.slots[key] = slot
+	if !s.scheduled {
+		s.scheduled = true
+		time.AfterFunc(checkpointFlushInterval, func() {
+			if err := s.Flush(); err != nil {
+				s.mu.Lock()
+				s.err = err
+				s.mu.Unlock()
+			}
+		})
+	}
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// Flush writes the saved checkpoints to the file now
+func (s *FileCheckpointStore) Flush() error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	s.mu.Lock()
+	s.scheduled = false
+	data, err := json.Marshal(s.slots)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoints: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save checkpoints: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save checkpoints: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save checkpoints: %w", err)
+	}
+	return nil
+}
+
+// checkpointKey identifies a subscription by its type and filters
+func checkpointKey(info SubscriptionInfo) string {
+	if len(info.Addresses) == 0 && len(info.Owners) == 0 {
+		return info.Type.String()
+	}
+	h := sha256.New()
+	for _, list := range [][]string{info.Addresses, info.Owners} {
+		for _, address := range slices.Sorted(slices.Values(list)) {
+			h.Write([]byte(address))
+			h.Write([]byte{0})
+		}
+		h.Write([]byte{1})
+	}
+	return fmt.Sprintf("%s-%x", info.Type, h.Sum(nil)[:8])
+}
+
+// loadCheckpoint restores the stream's checkpoint from the client's store
+func (rs *resumableStream) loadCheckpoint() {
+	store := rs.client.checkpoints
+	if store == nil {
+		return
+	}
+	slot, ok, err := store.Load(rs.checkpointKey)
+	if err != nil {
+		rs.client.emit(StatusEvent{Type: StatusCheckpointFailed, Stream: rs.kind, Err: err})
+		return
+	}
+	rs.observed, rs.hasCheckpoint = slot, ok
+}
+
+// slotOrdered reports whether streams of kind deliver their messages in slot
+// order. Slot statuses and account updates of several commitments interleave
+// across slots, so a later slot says nothing about earlier ones being
+// complete and such streams are not checked for gaps.
+func slotOrdered(kind SubscriptionType) bool {
+	return kind == SubscriptionTransactions || kind == SubscriptionWallets
+}
+
+// checkpoint advances the stream's checkpoint past msg. After a (re)connect,
+// the first message beyond the highest slot received reports a gap from the
+// checkpoint; messages of older slots, e.g. replayed by the server, neither
+// report nor clear it.
+func (rs *resumableStream) checkpoint(msg *pb.MessageWrapper) {
+	if !slotOrdered(rs.kind) {
+		return
+	}
+	slot := slotOf(msg)
+	if slot <= rs.highest {
+		return
+	}
+	if rs.resumed {
+		rs.resumed = false
+		if rs.hasCheckpoint && slot > rs.observed {
+			rs.client.reportGap(Gap{Stream: rs.kind, Key: rs.checkpointKey, FromSlot: rs.observed + 1, ToSlot: slot})
+		}
+	}
+
+	// Messages arrive in slot order, so earlier slots are complete
+	rs.highest = slot
+	if rs.hasCheckpoint && slot-1 <= rs.observed {
+		return
+	}
+	rs.observed, rs.hasCheckpoint = slot-1, true
+	if store := rs.client.checkpoints; store != nil {
+		if err := store.Save(rs.checkpointKey, rs.observed); err != nil {
+			rs.client.emit(StatusEvent{Type: StatusCheckpointFailed, Stream: rs.kind, Err: err})
+		}
+	}
+}
+
+func (c *Client) reportGap(gap Gap) {
+	c.emit(StatusEvent{Type: StatusGap, Stream: gap.Stream, Gap: &gap})
+	if c.onGap != nil {
+		c.onGap(gap)
+	}
+}
+
+// slotOf returns the slot a message belongs to, or 0 if it carries none
+func slotOf(msg *pb.MessageWrapper) uint64 {
+	switch {
+	case msg.GetTransaction() != nil:
+		return msg.GetTransaction().GetTransaction().GetSlot()
+	case msg.GetSlot() != nil:
+		return msg.GetSlot().GetSlot()
+	default:
+		return msg.GetAccountUpdate().GetSlot().GetSlot()
+	}
+}
@@ -0,0 +1,154 @@
+package thorclient
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// resume marks a reconnect in a checkpoint test's slot sequence
+const resume = 0
+
+func TestCheckpointGaps(t *testing.T) {
+	txMsg := func(slot uint64) *pb.MessageWrapper {
+		return &pb.MessageWrapper{EventMessage: &pb.MessageWrapper_Transaction{
+			Transaction: &pb.TransactionEventWrapper{Transaction: &pb.TransactionEvent{Slot: slot}},
+		}}
+	}
+	slotMsg := func(slot uint64) *pb.MessageWrapper {
+		return &pb.MessageWrapper{EventMessage: &pb.MessageWrapper_Slot{Slot: &pb.SlotStatusEvent{Slot: slot}}}
+	}
+
+	tests := []struct {
+		name     string
+		kind     SubscriptionType
+		msg      func(uint64) *pb.MessageWrapper
+		stored   uint64 // checkpoint from a previous run, 0 for none
+		slots    []uint64
+		gaps     []Gap
+		observed uint64
+	}{
+		{
+			name:     "first connect without checkpoint",
+			kind:     SubscriptionTransactions,
+			msg:      txMsg,
+			slots:    []uint64{100, 100, 101, 103},
+			observed: 102,
+		},
+		{
+			name:     "restart from checkpoint",
+			kind:     SubscriptionTransactions,
+			msg:      txMsg,
+			stored:   90,
+			slots:    []uint64{100, 101},
+			gaps:     []Gap{{FromSlot: 91, ToSlot: 100}},
+			observed: 100,
+		},
+		{
+			name:     "restart within checkpoint",
+			kind:     SubscriptionWallets,
+			msg:      txMsg,
+			stored:   100,
+			slots:    []uint64{100, 101},
+			observed: 100,
+		},
+		{
+			name:     "reconnect",
+			kind:     SubscriptionTransactions,
+			msg:      txMsg,
+			slots:    []uint64{100, 101, resume, 105},
+			gaps:     []Gap{{FromSlot: 101, ToSlot: 105}},
+			observed: 104,
+		},
+		{
+			name:     "older slots after reconnect do not consume the check",
+			kind:     SubscriptionWallets,
+			msg:      txMsg,
+			slots:    []uint64{100, 101, resume, 99, 101, 104, 106},
+			gaps:     []Gap{{FromSlot: 101, ToSlot: 104}},
+			observed: 105,
+		},
+		{
+			name:     "one check per reconnect",
+			kind:     SubscriptionTransactions,
+			msg:      txMsg,
+			slots:    []uint64{100, resume, 102, 104, resume, 106},
+			gaps:     []Gap{{FromSlot: 100, ToSlot: 102}, {FromSlot: 104, ToSlot: 106}},
+			observed: 105,
+		},
+		{
+			name:   "slot statuses are not slot-ordered",
+			kind:   SubscriptionSlots,
+			msg:    slotMsg,
+			stored: 50,
+			slots:  []uint64{100, 68, resume, 120},
+			// The stored checkpoint is left alone
+			observed: 50,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gaps []Gap
+			store := NewMemoryCheckpointStore()
+			if tt.stored != 0 {
+				store.Save("key", tt.stored)
+			}
+			c := &Client{
+				status:      make(chan StatusEvent, 16),
+				checkpoints: store,
+				onGap:       func(g Gap) { gaps = append(gaps, g) },
+			}
+			rs := &resumableStream{client: c, kind: tt.kind, checkpointKey: "key"}
+			rs.loadCheckpoint()
+			rs.resumed = true
+
+			for _, slot := range tt.slots {
+				if slot == resume {
+					rs.resumed = true
+					continue
+				}
+				rs.checkpoint(tt.msg(slot))
+			}
+
+			for i := range tt.gaps {
+				tt.gaps[i].Stream, tt.gaps[i].Key = tt.kind, "key"
+			}
+			if !slices.Equal(gaps, tt.gaps) {
+				t.Errorf("gaps = %+v, want %+v", gaps, tt.gaps)
+			}
+			if slot, _, _ := store.Load("key"); slot != tt.observed {
+				t.Errorf("checkpoint = %d, want %d", slot, tt.observed)
+			}
+		})
+	}
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	store, err := NewFileCheckpointStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for slot := uint64(1); slot <= 100; slot++ {
+		if err := store.Save("transactions", slot); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("saves were written before the flush interval")
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileCheckpointStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slot, ok, _ := reopened.Load("transactions"); !ok || slot != 100 {
+		t.Errorf("Load = %d, %v, want 100, true", slot, ok)
+	}
+}
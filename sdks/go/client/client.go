@@ -2,212 +2,322 @@ package thorclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"io"
+	"slices"
+	"sync"
 	"time"
 
 	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
 )
 
 type Client struct {
-	conn           *grpc.ClientConn
-	eventClient    pb.EventPublisherClient
-	thorClient     pb.ThorStreamerClient
-	token          string
+	endpoints      []*endpoint
+	creds          credentials.PerRPCCredentials
 	defaultTimeout time.Duration
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	updateOverlap  time.Duration
+	watchdog       Watchdog
+	checkpoints    CheckpointStore
+	onGap          func(Gap)
+	status         chan StatusEvent
+	registry       *registry
+
+	failbackAfter time.Duration
+	mu            sync.Mutex // guards the failover state below
+	active        int
+	probing       bool
+	streams       map[*resumableStream]struct{}
+	done          chan struct{}
+	closeOnce     sync.Once
 }
 
 type Config struct {
-	ServerAddr     string
-	Token          string
+	ServerAddr string
+	// ServerAddrs lists endpoints in order of preference, instead of
+	// ServerAddr. Streams fail over to the next endpoint when the current one
+	// is unavailable, stalls or exhausts MaxRetries.
+	ServerAddrs []string
+	// FailbackAfter is how long the first of ServerAddrs must be reachable
+	// before streams return to it (default 1m). A negative value disables
+	// failback.
+	FailbackAfter time.Duration
+	Token         string
+	// Credentials supplies per-call authorization instead of Token, e.g. to
+	// fetch tokens from a secret store. Defaults to TokenCredentials for Token.
+	Credentials credentials.PerRPCCredentials
+	// DefaultTimeout is the stall timeout of transaction and thor streams
+	// (default 30s), see Watchdog
 	DefaultTimeout time.Duration
-	MaxRetries     int
-}
-
-type TransactionStream struct {
-	stream pb.EventPublisher_SubscribeToTransactionsClient
-	ctx    context.Context
-}
-
-type SlotStream struct {
-	stream pb.EventPublisher_SubscribeToSlotStatusClient
-}
-
-type WalletStream struct {
-	stream pb.EventPublisher_SubscribeToWalletTransactionsClient
-}
-
-type AccountStream struct {
-	stream pb.EventPublisher_SubscribeToAccountUpdatesClient
-}
-
-type ThorStream struct {
-	stream pb.ThorStreamer_StreamUpdatesClient
+	// MaxRetries is the number of consecutive reconnect attempts made for a
+	// stream before Recv gives up (default 5). A negative value disables
+	// reconnection.
+	MaxRetries int
+	// InitialBackoff is the delay before the first reconnect attempt (default 1s).
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between reconnect attempts (default 30s).
+	MaxBackoff time.Duration
+	// UpdateOverlap is how long the old subscription keeps running after a
+	// wallet or account stream is updated (default 5s)
+	UpdateOverlap time.Duration
+	// Watchdog configures detection of streams that stop delivering messages
+	Watchdog Watchdog
+	// Checkpoints persists each subscription's last fully observed slot. Gaps
+	// after a reconnect are detected without it, but not across restarts.
+	Checkpoints CheckpointStore
+	// OnGap is called with every gap detected, in addition to the StatusGap
+	// event. It runs on the goroutine calling Recv.
+	OnGap func(Gap)
+
+	// TLS enables transport security. Nil dials without TLS.
+	TLS *TLSConfig
+	// Keepalive configures client keepalive pings. Nil keeps the gRPC defaults.
+	Keepalive *keepalive.ClientParameters
+	// Compression names a registered compressor, e.g. "gzip". Empty disables compression.
+	Compression string
+	// MaxRecvMsgSize and MaxSendMsgSize limit message sizes in bytes (default 100 MB).
+	MaxRecvMsgSize int
+	MaxSendMsgSize int
+	// DialOptions are applied after the options derived from the fields above
+	DialOptions []grpc.DialOption
+
+	// Limits are the subscription quotas checked before opening a stream
+	// (default DefaultLimits)
+	Limits Limits
 }
 
 func NewClient(cfg Config) (*Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
 	if cfg.DefaultTimeout == 0 {
 		cfg.DefaultTimeout = 30 * time.Second
 	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.InitialBackoff == 0 {
+		cfg.InitialBackoff = time.Second
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.MaxBackoff < cfg.InitialBackoff {
+		cfg.MaxBackoff = cfg.InitialBackoff
+	}
+	if cfg.UpdateOverlap == 0 {
+		cfg.UpdateOverlap = 5 * time.Second
+	}
+	if cfg.FailbackAfter == 0 {
+		cfg.FailbackAfter = time.Minute
+	}
+	if cfg.Credentials == nil {
+		cfg.Credentials = NewTokenCredentials(cfg.Token)
+	}
 
-	conn, err := grpc.NewClient(
-		cfg.ServerAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithDefaultCallOptions(
-			grpc.MaxCallRecvMsgSize(100*1024*1024),
-			grpc.MaxCallSendMsgSize(100*1024*1024),
-		),
-	)
+	opts, err := cfg.dialOptions()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect: %w", err)
+		return nil, err
+	}
+
+	addrs := cfg.ServerAddrs
+	if len(addrs) == 0 {
+		addrs = []string{cfg.ServerAddr}
+	}
+	endpoints := make([]*endpoint, 0, len(addrs))
+	for _, addr := range addrs {
+		conn, err := grpc.NewClient(addr, opts...)
+		if err != nil {
+			for _, ep := range endpoints {
+				ep.conn.Close()
+			}
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		endpoints = append(endpoints, newEndpoint(addr, conn))
 	}
 
 	return &Client{
-		conn:           conn,
-		eventClient:    pb.NewEventPublisherClient(conn),
-		thorClient:     pb.NewThorStreamerClient(conn),
-		token:          cfg.Token,
+		endpoints:      endpoints,
+		creds:          cfg.Credentials,
 		defaultTimeout: cfg.DefaultTimeout,
+		maxRetries:     cfg.MaxRetries,
+		initialBackoff: cfg.InitialBackoff,
+		maxBackoff:     cfg.MaxBackoff,
+		updateOverlap:  cfg.UpdateOverlap,
+		watchdog:       cfg.Watchdog,
+		checkpoints:    cfg.Checkpoints,
+		onGap:          cfg.OnGap,
+		status:         make(chan StatusEvent, statusBufferSize),
+		registry:       newRegistry(cfg.Limits),
+		failbackAfter:  cfg.FailbackAfter,
+		streams:        make(map[*resumableStream]struct{}),
+		done:           make(chan struct{}),
 	}, nil
 }
 
 func (c *Client) Close() error {
-	return c.conn.Close()
-}
+	c.closeOnce.Do(func() { close(c.done) })
 
-// contextWithAuth adds authentication token to context
-func (c *Client) contextWithAuth(ctx context.Context) context.Context {
-	return metadata.AppendToOutgoingContext(ctx, "authorization", c.token)
+	var errs []error
+	for _, ep := range c.endpoints {
+		errs = append(errs, ep.conn.Close())
+	}
+	if store, ok := c.checkpoints.(interface{ Flush() error }); ok {
+		errs = append(errs, store.Flush())
+	}
+	return errors.Join(errs...)
 }
 
 // SubscribeToTransactions subscribes to transaction events
 func (c *Client) SubscribeToTransactions(ctx context.Context) (*TransactionStream, error) {
-	authCtx := c.contextWithAuth(ctx)
-	stream, err := c.eventClient.SubscribeToTransactions(authCtx, &emptypb.Empty{}) // Changed here
-	if err != nil {
-		return nil, fmt.Errorf("failed to subscribe: %w", err)
-	}
-
-	return &TransactionStream{stream: stream, ctx: authCtx}, nil
-}
-
-// Recv receives the next transaction message
-func (ts *TransactionStream) Recv() (*pb.MessageWrapper, error) {
-	resp, err := ts.stream.Recv()
+	stream, err := c.openStream(ctx, SubscriptionInfo{Type: SubscriptionTransactions}, func(ctx context.Context, ep *endpoint) (recvFunc, error) {
+		stream, err := ep.eventClient.SubscribeToTransactions(ctx, &emptypb.Empty{})
+		if err != nil {
+			return nil, err
+		}
+		return unwrapResponses(stream), nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	var wrapper pb.MessageWrapper
-	if err := proto.Unmarshal(resp.Data, &wrapper); err != nil { // Changed here
-		return nil, fmt.Errorf("failed to unmarshal: %w", err)
-	}
-	return &wrapper, nil
+	return newStream(stream, transactionEvent), nil
 }
 
 // SubscribeToSlotStatus subscribes to slot status events
 func (c *Client) SubscribeToSlotStatus(ctx context.Context) (*SlotStream, error) {
-	authCtx := c.contextWithAuth(ctx)
-	stream, err := c.eventClient.SubscribeToSlotStatus(authCtx, &emptypb.Empty{}) // Changed here
-	if err != nil {
-		return nil, fmt.Errorf("failed to subscribe: %w", err)
-	}
-
-	return &SlotStream{stream: stream}, nil
-}
-
-// Recv receives the next slot status message
-func (ss *SlotStream) Recv() (*pb.MessageWrapper, error) {
-	resp, err := ss.stream.Recv()
+	stream, err := c.openStream(ctx, SubscriptionInfo{Type: SubscriptionSlots}, func(ctx context.Context, ep *endpoint) (recvFunc, error) {
+		stream, err := ep.eventClient.SubscribeToSlotStatus(ctx, &emptypb.Empty{})
+		if err != nil {
+			return nil, err
+		}
+		return unwrapResponses(stream), nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	var wrapper pb.MessageWrapper
-	if err := proto.Unmarshal(resp.Data, &wrapper); err != nil { // Changed here
-		return nil, fmt.Errorf("failed to unmarshal: %w", err)
-	}
-	return &wrapper, nil
+	return newStream(stream, slotEvent), nil
 }
 
-// WalletStream represents a wallet transaction subscription
-
 // SubscribeToWalletTransactions subscribes to wallet transaction events
 func (c *Client) SubscribeToWalletTransactions(ctx context.Context, wallets []string) (*WalletStream, error) {
-	authCtx := c.contextWithAuth(ctx)
-	req := &pb.SubscribeWalletRequest{WalletAddress: wallets}
-	stream, err := c.eventClient.SubscribeToWalletTransactions(authCtx, req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	if err := c.registry.validateWallets(wallets); err != nil {
+		return nil, err
 	}
 
-	return &WalletStream{stream: stream}, nil
-}
-
-// Recv receives the next wallet transaction message
-func (ws *WalletStream) Recv() (*pb.MessageWrapper, error) {
-	resp, err := ws.stream.Recv()
+	wallets = slices.Clone(wallets)
+	ctx, cancel := context.WithCancel(ctx)
+	source, err := c.openWallets(ctx, wallets)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	var wrapper pb.MessageWrapper
-	if err := proto.Unmarshal(resp.Data, &wrapper); err != nil { // Changed here
-		return nil, fmt.Errorf("failed to unmarshal: %w", err)
-	}
-	return &wrapper, nil
+	return &WalletStream{
+		Stream:  newUpdatableStream(ctx, cancel, source, transactionEvent, SignatureKey),
+		client:  c,
+		wallets: wallets,
+	}, nil
+}
+
+// openWallets opens a wallet subscription for already validated addresses
+func (c *Client) openWallets(ctx context.Context, wallets []string) (*resumableStream, error) {
+	req := &pb.SubscribeWalletRequest{WalletAddress: wallets}
+	info := SubscriptionInfo{Type: SubscriptionWallets, Addresses: req.WalletAddress}
+	return c.openStream(ctx, info, func(ctx context.Context, ep *endpoint) (recvFunc, error) {
+		stream, err := ep.eventClient.SubscribeToWalletTransactions(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return unwrapResponses(stream), nil
+	})
 }
 
 // SubscribeToAccountUpdates subscribes to account update events
 func (c *Client) SubscribeToAccountUpdates(ctx context.Context, accounts, owners []string) (*AccountStream, error) {
-	authCtx := c.contextWithAuth(ctx)
-	req := &pb.SubscribeAccountsRequest{
-		AccountAddress: accounts,
-		OwnerAddress:   owners,
-	}
-	stream, err := c.eventClient.SubscribeToAccountUpdates(authCtx, req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	if err := c.registry.validateAccounts(accounts, owners); err != nil {
+		return nil, err
 	}
 
-	return &AccountStream{stream: stream}, nil
-}
-
-// Recv receives the next account update message
-func (as *AccountStream) Recv() (*pb.MessageWrapper, error) {
-	resp, err := as.stream.Recv()
+	accounts, owners = slices.Clone(accounts), slices.Clone(owners)
+	ctx, cancel := context.WithCancel(ctx)
+	source, err := c.openAccounts(ctx, accounts, owners)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	var wrapper pb.MessageWrapper
-	if err := proto.Unmarshal(resp.Data, &wrapper); err != nil { // Changed here
-		return nil, fmt.Errorf("failed to unmarshal: %w", err)
+	return &AccountStream{
+		Stream:   newUpdatableStream(ctx, cancel, source, accountUpdate, AccountVersionKey),
+		client:   c,
+		accounts: accounts,
+		owners:   owners,
+	}, nil
+}
+
+// openAccounts opens an account subscription for already validated addresses
+func (c *Client) openAccounts(ctx context.Context, accounts, owners []string) (*resumableStream, error) {
+	req := &pb.SubscribeAccountsRequest{
+		AccountAddress: accounts,
+		OwnerAddress:   owners,
 	}
-	return &wrapper, nil
+	info := SubscriptionInfo{Type: SubscriptionAccounts, Addresses: req.AccountAddress, Owners: req.OwnerAddress}
+	return c.openStream(ctx, info, func(ctx context.Context, ep *endpoint) (recvFunc, error) {
+		stream, err := ep.eventClient.SubscribeToAccountUpdates(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		return unwrapResponses(stream), nil
+	})
 }
 
 // SubscribeToThorUpdates subscribes to Thor update events
 func (c *Client) SubscribeToThorUpdates(ctx context.Context) (*ThorStream, error) {
-	stream, err := c.thorClient.StreamUpdates(ctx, &pb.Empty{})
+	stream, err := c.openStream(ctx, SubscriptionInfo{Type: SubscriptionThor}, func(ctx context.Context, ep *endpoint) (recvFunc, error) {
+		stream, err := ep.thorClient.StreamUpdates(ctx, &pb.Empty{})
+		if err != nil {
+			return nil, err
+		}
+		return stream.Recv, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to subscribe: %w", err)
+		return nil, err
 	}
 
-	return &ThorStream{stream: stream}, nil
+	return newStream(stream, anyEvent), nil
 }
 
-// Recv receives the next Thor update message
-func (ts *ThorStream) Recv() (*pb.MessageWrapper, error) {
-	return ts.stream.Recv()
+// unwrapResponses adapts an EventPublisher stream, whose responses carry an
+// encoded MessageWrapper, to a recvFunc
+func unwrapResponses(stream interface {
+	Recv() (*pb.StreamResponse, error)
+}) recvFunc {
+	return func() (*pb.MessageWrapper, error) {
+		resp, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		var wrapper pb.MessageWrapper
+		if err := proto.Unmarshal(resp.Data, &wrapper); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal: %w", err)
+		}
+		return &wrapper, nil
+	}
 }
 
 // Helper function to check if stream is done
 func IsStreamDone(err error) bool {
-	return err == io.EOF || err == context.Canceled
+	return errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) || status.Code(err) == codes.Canceled
 }
@@ -0,0 +1,175 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"maps"
+	"slices"
+	"sync"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// CommitmentOptions configures WithCommitment
+type CommitmentOptions struct {
+	// OnDropped is called with the buffered transactions of a slot that will
+	// not reach the commitment: err is ErrSlotSkipped for skipped and forked
+	// off slots, or ErrSlotUntracked for slots too old for the tracker
+	OnDropped func(slot uint64, txs []*pb.TransactionEvent, err error)
+}
+
+// CommitmentStream delivers transactions once their slot has reached a
+// commitment, in slot order among slots released together
+type CommitmentStream struct {
+	ctx        context.Context
+	tracker    *SlotTracker
+	commitment Commitment
+	opts       CommitmentOptions
+	closers    []func()
+
+	txs chan txResult
+	// mu guards pending, which only Recv modifies, against Pending
+	mu      sync.Mutex
+	pending map[uint64][]*pb.TransactionEvent
+	ready   []*pb.TransactionEvent
+}
+
+type txResult struct {
+	tx  *pb.TransactionEvent
+	err error
+}
+
+// WithCommitment buffers the transactions of txs per slot and releases them
+// when tracker reports their slot at commitment c. It reads txs until ctx is
+// done or the stream fails.
+func WithCommitment(ctx context.Context, txs Receiver[*pb.TransactionEvent], tracker *SlotTracker, c Commitment, opts CommitmentOptions) *CommitmentStream {
+	s := &CommitmentStream{
+		ctx:        ctx,
+		tracker:    tracker,
+		commitment: c,
+		opts:       opts,
+		txs:        make(chan txResult, 256),
+		pending:    make(map[uint64][]*pb.TransactionEvent),
+	}
+	go s.pump(txs)
+	return s
+}
+
+// SubscribeToTransactionsWithCommitment subscribes to transactions and slot
+// statuses, delivering each transaction once its slot reaches c. It uses two
+// subscriptions.
+func (c *Client) SubscribeToTransactionsWithCommitment(ctx context.Context, commitment Commitment, opts CommitmentOptions) (*CommitmentStream, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	slots, err := c.SubscribeToSlotStatus(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	txs, err := c.SubscribeToTransactions(ctx)
+	if err != nil {
+		slots.Close()
+		cancel()
+		return nil, err
+	}
+
+	s := WithCommitment(ctx, txs, TrackSlots(ctx, slots), commitment, opts)
+	s.closers = []func(){cancel, txs.Close, slots.Close}
+	return s, nil
+}
+
+// Recv blocks until the next transaction whose slot reached the commitment
+func (s *CommitmentStream) Recv() (*pb.TransactionEvent, error) {
+	for {
+		if len(s.ready) > 0 {
+			tx := s.ready[0]
+			s.ready[0] = nil
+			s.ready = s.ready[1:]
+			return tx, nil
+		}
+
+		changed := s.tracker.changes()
+		if err := s.settle(); err != nil {
+			return nil, err
+		}
+		if len(s.ready) > 0 {
+			continue
+		}
+
+		select {
+		case r := <-s.txs:
+			if r.err != nil {
+				return nil, r.err
+			}
+			s.mu.Lock()
+			s.pending[r.tx.Slot] = append(s.pending[r.tx.Slot], r.tx)
+			s.mu.Unlock()
+		case <-changed:
+		case <-s.ctx.Done():
+			return nil, s.ctx.Err()
+		}
+	}
+}
+
+// All returns an iterator over the released transactions, see Stream.All
+func (s *CommitmentStream) All() iter.Seq2[*pb.TransactionEvent, error] {
+	return all(s)
+}
+
+// Close ends the subscriptions opened by SubscribeToTransactionsWithCommitment.
+// Streams passed to WithCommitment are left to the caller.
+func (s *CommitmentStream) Close() {
+	for _, closer := range s.closers {
+		closer()
+	}
+}
+
+// Pending returns the number of transactions waiting for their slot
+func (s *CommitmentStream) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, txs := range s.pending {
+		n += len(txs)
+	}
+	return n
+}
+
+// settle releases or drops the buffered slots the tracker has decided on
+func (s *CommitmentStream) settle() error {
+	for _, slot := range slices.Sorted(maps.Keys(s.pending)) {
+		err, ok := s.tracker.outcome(slot, s.commitment)
+		if !ok {
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrSlotSkipped) && !errors.Is(err, ErrSlotUntracked) {
+			// The tracker stopped and can no longer decide
+			return err
+		}
+
+		txs := s.pending[slot]
+		s.mu.Lock()
+		delete(s.pending, slot)
+		s.mu.Unlock()
+		if err == nil {
+			s.ready = append(s.ready, txs...)
+		} else if s.opts.OnDropped != nil {
+			s.opts.OnDropped(slot, txs, err)
+		}
+	}
+	return nil
+}
+
+func (s *CommitmentStream) pump(txs Receiver[*pb.TransactionEvent]) {
+	for {
+		tx, err := txs.Recv()
+		select {
+		case s.txs <- txResult{tx: tx, err: err}:
+		case <-s.ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
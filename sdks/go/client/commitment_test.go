@@ -0,0 +1,184 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// receive forwards the values of r to the returned channel until Recv fails
+func receive[T any](r Receiver[T]) <-chan T {
+	out := make(chan T, 64)
+	go func() {
+		defer close(out)
+		for {
+			v, err := r.Recv()
+			if err != nil {
+				return
+			}
+			out <- v
+		}
+	}()
+	return out
+}
+
+// droppedTxs records the transactions passed to an OnDropped callback
+type droppedTxs struct {
+	mu    sync.Mutex
+	slots map[uint64]error
+	count int
+}
+
+func (d *droppedTxs) add(slot uint64, txs []*pb.TransactionEvent, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.slots == nil {
+		d.slots = make(map[uint64]error)
+	}
+	d.slots[slot] = err
+	d.count += len(txs)
+}
+
+func (d *droppedTxs) len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.count
+}
+
+func (d *droppedTxs) err(slot uint64) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.slots[slot]
+}
+
+func TestWithCommitment(t *testing.T) {
+	const (
+		processed = int32(CommitmentProcessed)
+		confirmed = int32(CommitmentConfirmed)
+		rooted    = int32(CommitmentRooted)
+	)
+
+	tests := []struct {
+		name       string
+		commitment Commitment
+		txs        []uint64 // slots of the transactions, in arrival order
+		events     []slotUpdate
+		want       []uint64 // slots of the released transactions
+		dropped    map[uint64]error
+		pending    int
+	}{
+		{
+			name:       "released in slot order",
+			commitment: CommitmentConfirmed,
+			txs:        []uint64{101, 100, 101},
+			events:     []slotUpdate{{100, 99, processed}, {101, 100, confirmed}},
+			want:       []uint64{100, 101, 101},
+		},
+		{
+			name:       "held below the commitment",
+			commitment: CommitmentConfirmed,
+			txs:        []uint64{100, 101},
+			events:     []slotUpdate{{100, 99, confirmed}, {101, 100, processed}},
+			want:       []uint64{100},
+			pending:    1,
+		},
+		{
+			name:       "processed",
+			commitment: CommitmentProcessed,
+			txs:        []uint64{100},
+			events:     []slotUpdate{{100, 99, processed}},
+			want:       []uint64{100},
+		},
+		{
+			name:       "forked off slot dropped",
+			commitment: CommitmentRooted,
+			txs:        []uint64{101, 102},
+			events:     []slotUpdate{{100, 99, processed}, {101, 100, processed}, {102, 100, rooted}},
+			want:       []uint64{102},
+			dropped:    map[uint64]error{101: ErrSlotSkipped},
+		},
+		{
+			name:       "untracked slot dropped",
+			commitment: CommitmentConfirmed,
+			txs:        []uint64{50},
+			events:     []slotUpdate{{100, 99, rooted}},
+			dropped:    map[uint64]error{50: ErrSlotUntracked},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			tracker, _ := newTestTracker(t)
+			txs := make(chan *pb.TransactionEvent, len(tt.txs))
+			var dropped droppedTxs
+			s := WithCommitment(ctx, chanReceiver[*pb.TransactionEvent]{ctx, txs}, tracker, tt.commitment, CommitmentOptions{OnDropped: dropped.add})
+			released := receive(s)
+
+			for i, slot := range tt.txs {
+				txs <- &pb.TransactionEvent{Slot: slot, Index: uint64(i)}
+			}
+			eventually(t, "transactions to be buffered", func() bool { return s.Pending() == len(tt.txs) })
+			for _, ev := range tt.events {
+				tracker.observe(ev.proto())
+			}
+
+			for _, want := range tt.want {
+				select {
+				case tx := <-released:
+					if tx.Slot != want {
+						t.Errorf("released a transaction of slot %d, want slot %d", tx.Slot, want)
+					}
+				case <-time.After(time.Second):
+					t.Fatalf("slot %d was not released", want)
+				}
+			}
+			wantDropped := 0
+			for slot, err := range tt.dropped {
+				eventually(t, "dropped transactions", func() bool { return dropped.err(slot) != nil })
+				if got := dropped.err(slot); !errors.Is(got, err) {
+					t.Errorf("slot %d dropped with %v, want %v", slot, got, err)
+				}
+				for _, txSlot := range tt.txs {
+					if txSlot == slot {
+						wantDropped++
+					}
+				}
+			}
+			if got := dropped.len(); got != wantDropped {
+				t.Errorf("dropped %d transactions, want %d", got, wantDropped)
+			}
+			eventually(t, "pending transactions to settle", func() bool { return s.Pending() == tt.pending })
+		})
+	}
+}
+
+func TestWithCommitmentTrackerStopped(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tracker, stop := newTestTracker(t)
+	txs := make(chan *pb.TransactionEvent, 1)
+	s := WithCommitment(ctx, chanReceiver[*pb.TransactionEvent]{ctx, txs}, tracker, CommitmentConfirmed, CommitmentOptions{})
+
+	txs <- &pb.TransactionEvent{Slot: 100}
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.Recv()
+		errc <- err
+	}()
+	eventually(t, "the transaction to be buffered", func() bool { return s.Pending() == 1 })
+	stop()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, ErrStreamClosed) {
+			t.Errorf("Recv after the tracker stopped = %v, want ErrStreamClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Recv did not return after the tracker stopped")
+	}
+}
@@ -0,0 +1,186 @@
+package thorclient
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Receiver is a source of events, such as a Stream
+type Receiver[T any] interface {
+	Recv() (T, error)
+}
+
+// OverflowPolicy decides what happens to a message when the consume queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock stops reading from the stream until a worker frees a slot
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the incoming message
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest queued message to make room
+	OverflowDropOldest
+)
+
+// ConsumeOptions configures Consume
+type ConsumeOptions struct {
+	// Workers is the number of goroutines running the handler (default runtime.NumCPU())
+	Workers int
+	// QueueSize bounds the number of messages waiting for a worker (default 1024)
+	QueueSize int
+	// Overflow selects the behaviour when the queue is full (default OverflowBlock)
+	Overflow OverflowPolicy
+	// OnError is called with errors returned by the handler
+	OnError func(error)
+}
+
+// ConsumeStats is a snapshot of a Consumer's counters
+type ConsumeStats struct {
+	Received      uint64
+	Processed     uint64
+	Failed        uint64
+	Dropped       uint64
+	QueueDepth    int
+	QueueCapacity int
+}
+
+// Consumer reads a stream on a dedicated goroutine and hands its messages to
+// a bounded pool of workers
+type Consumer struct {
+	received  atomic.Uint64
+	processed atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+
+	depth    func() int
+	capacity int
+
+	done chan struct{}
+	err  error
+}
+
+// Consume starts processing stream with handler and returns immediately. It
+// stops when the stream ends or ctx is cancelled; ctx should also bound the
+// stream so that a blocked Recv returns.
+func Consume[T any](ctx context.Context, stream Receiver[T], handler func(context.Context, T) error, opts ConsumeOptions) *Consumer {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+
+	queue := make(chan T, opts.QueueSize)
+	c := &Consumer{
+		depth:    func() int { return len(queue) },
+		capacity: opts.QueueSize,
+		done:     make(chan struct{}),
+	}
+
+	var workers sync.WaitGroup
+	for range opts.Workers {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			work(ctx, c, queue, handler, opts.OnError)
+		}()
+	}
+
+	go func() {
+		c.err = fill(ctx, c, stream, queue, opts.Overflow)
+		close(queue)
+		workers.Wait()
+		close(c.done)
+	}()
+
+	return c
+}
+
+// work runs handler on messages from queue until it is closed or ctx is done
+func work[T any](ctx context.Context, c *Consumer, queue <-chan T, handler func(context.Context, T) error, onError func(error)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-queue:
+			if !ok {
+				return
+			}
+			if err := handler(ctx, msg); err != nil {
+				c.failed.Add(1)
+				if onError != nil {
+					onError(err)
+				}
+			}
+			c.processed.Add(1)
+		}
+	}
+}
+
+// fill pulls messages from stream into queue until the stream ends
+func fill[T any](ctx context.Context, c *Consumer, stream Receiver[T], queue chan T, overflow OverflowPolicy) error {
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			if IsStreamDone(err) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		c.received.Add(1)
+
+		switch overflow {
+		case OverflowDropNewest:
+			select {
+			case queue <- msg:
+			default:
+				c.dropped.Add(1)
+			}
+		case OverflowDropOldest:
+			for sent := false; !sent; {
+				select {
+				case queue <- msg:
+					sent = true
+				default:
+					select {
+					case <-queue:
+						c.dropped.Add(1)
+					default:
+					}
+				}
+			}
+		default:
+			select {
+			case queue <- msg:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+// Stats returns the current counters
+func (c *Consumer) Stats() ConsumeStats {
+	return ConsumeStats{
+		Received:      c.received.Load(),
+		Processed:     c.processed.Load(),
+		Failed:        c.failed.Load(),
+		Dropped:       c.dropped.Load(),
+		QueueDepth:    c.depth(),
+		QueueCapacity: c.capacity,
+	}
+}
+
+// Done is closed once the stream has ended and all workers have returned
+func (c *Consumer) Done() <-chan struct{} {
+	return c.done
+}
+
+// Wait blocks until the consumer stops and returns the error that ended the
+// stream, or nil if it ended normally
+func (c *Consumer) Wait() error {
+	<-c.done
+	return c.err
+}
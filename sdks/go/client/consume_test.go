@@ -0,0 +1,182 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// chanReceiver delivers the values sent on ch until ch is closed or its
+// context is done
+type chanReceiver[T any] struct {
+	ctx context.Context
+	ch  chan T
+}
+
+func (r chanReceiver[T]) Recv() (T, error) {
+	var zero T
+	select {
+	case v, ok := <-r.ch:
+		if !ok {
+			return zero, io.EOF
+		}
+		return v, nil
+	case <-r.ctx.Done():
+		return zero, r.ctx.Err()
+	}
+}
+
+func TestConsumeOverflow(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    OverflowPolicy
+		processed []int
+		dropped   uint64
+	}{
+		{"block", OverflowBlock, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, 0},
+		{"drop newest", OverflowDropNewest, []int{0, 1, 2}, 7},
+		{"drop oldest", OverflowDropOldest, []int{0, 8, 9}, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			source := make(chan int)
+			started, release := make(chan struct{}), make(chan struct{})
+			var mu sync.Mutex
+			var processed []int
+			c := Consume(ctx, chanReceiver[int]{ctx, source}, func(ctx context.Context, v int) error {
+				if v == 0 {
+					close(started)
+					<-release
+				}
+				mu.Lock()
+				processed = append(processed, v)
+				mu.Unlock()
+				return nil
+			}, ConsumeOptions{Workers: 1, QueueSize: 2, Overflow: tt.policy})
+
+			source <- 0
+			<-started
+			go func() {
+				for v := 1; v < 10; v++ {
+					source <- v
+				}
+				close(source)
+			}()
+			if tt.policy == OverflowBlock {
+				// One message in the handler, two queued and one held by the reader
+				eventually(t, "the queue to fill", func() bool { return c.Stats().QueueDepth == 2 })
+				time.Sleep(10 * time.Millisecond)
+				if stats := c.Stats(); stats.Received != 4 {
+					t.Errorf("received %d messages while blocked, want 4", stats.Received)
+				}
+			} else {
+				eventually(t, "messages to be dropped", func() bool { return c.Stats().Dropped == tt.dropped })
+			}
+			close(release)
+
+			if err := c.Wait(); err != nil {
+				t.Fatalf("Wait() = %v", err)
+			}
+			if !slices.Equal(processed, tt.processed) {
+				t.Errorf("processed %v, want %v", processed, tt.processed)
+			}
+			stats := c.Stats()
+			if stats.Received != 10 || stats.Processed != uint64(len(tt.processed)) || stats.Dropped != tt.dropped {
+				t.Errorf("stats %+v, want 10 received, %d processed, %d dropped", stats, len(tt.processed), tt.dropped)
+			}
+		})
+	}
+}
+
+func TestConsume(t *testing.T) {
+	errOdd := errors.New("odd")
+	errBroken := errors.New("broken stream")
+
+	tests := []struct {
+		name      string
+		streamErr error // ends the stream after the messages, EOF if nil
+		wantErr   error
+	}{
+		{name: "stream ends"},
+		{name: "stream fails", streamErr: errBroken, wantErr: errBroken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const n = 100
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			source := make(chan int, n)
+			for v := range n {
+				source <- v
+			}
+			close(source)
+			var stream Receiver[int] = chanReceiver[int]{ctx, source}
+			if tt.streamErr != nil {
+				stream = failingReceiver[int]{stream, tt.streamErr}
+			}
+
+			var mu sync.Mutex
+			var errs []error
+			c := Consume(ctx, stream, func(ctx context.Context, v int) error {
+				if v%2 == 1 {
+					return errOdd
+				}
+				return nil
+			}, ConsumeOptions{Workers: 4, OnError: func(err error) {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}})
+
+			if err := c.Wait(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Wait() = %v, want %v", err, tt.wantErr)
+			}
+			select {
+			case <-c.Done():
+			default:
+				t.Error("Done not closed after Wait returned")
+			}
+			stats := c.Stats()
+			if stats.Received != n || stats.Processed != n || stats.Failed != n/2 || stats.QueueCapacity != 1024 {
+				t.Errorf("stats %+v, want %d received and processed, %d failed, capacity 1024", stats, n, n/2)
+			}
+			if len(errs) != n/2 {
+				t.Errorf("OnError called %d times, want %d", len(errs), n/2)
+			}
+		})
+	}
+}
+
+func TestConsumeCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := Consume(ctx, chanReceiver[int]{ctx, make(chan int)}, func(context.Context, int) error { return nil }, ConsumeOptions{})
+	cancel()
+	select {
+	case <-c.Done():
+		if err := c.Wait(); err != nil {
+			t.Errorf("Wait() after cancel = %v, want nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("consumer did not stop after cancel")
+	}
+}
+
+// failingReceiver returns err once r is exhausted
+type failingReceiver[T any] struct {
+	r   Receiver[T]
+	err error
+}
+
+func (f failingReceiver[T]) Recv() (T, error) {
+	v, err := f.r.Recv()
+	if errors.Is(err, io.EOF) {
+		return v, f.err
+	}
+	return v, err
+}
@@ -0,0 +1,85 @@
+package thorclient
+
+import (
+	"encoding/binary"
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// dedupWindow is the number of recent events remembered to drop duplicates
+// delivered by overlapping subscriptions
+const dedupWindow = 4096
+
+// recentSet remembers the most recent keys up to a fixed capacity
+type recentSet struct {
+	mu    sync.Mutex
+	keys  map[string]arrival
+	order []string
+	next  int
+}
+
+func newRecentSet(capacity int) *recentSet {
+	return &recentSet{
+		keys:  make(map[string]arrival, capacity),
+		order: make([]string, capacity),
+	}
+}
+
+// arrival is when and from which source a key was first seen
+type arrival struct {
+	at     time.Time
+	source int
+}
+
+// add records key, reporting false if it was already present
+func (r *recentSet) add(key string) bool {
+	_, added := r.addAt(key, arrival{})
+	return added
+}
+
+// addAt records key as seen at a. If key was already present it reports false
+// and the earliest arrival recorded before; an earlier a replaces it.
+func (r *recentSet) addAt(key string, a arrival) (arrival, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if first, ok := r.keys[key]; ok {
+		if a.at.Before(first.at) {
+			r.keys[key] = a
+		}
+		return first, false
+	}
+	if evicted := r.order[r.next]; evicted != "" {
+		delete(r.keys, evicted)
+	}
+	r.order[r.next] = key
+	r.next = (r.next + 1) % len(r.order)
+	r.keys[key] = a
+	return a, true
+}
+
+// contains reports whether key is present
+func (r *recentSet) contains(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.keys[key]
+	return ok
+}
+
+// SignatureKey identifies a transaction by its signature
+func SignatureKey(tx *pb.TransactionEvent) string {
+	return string(tx.GetSignature())
+}
+
+// SlotStatusKey identifies a slot status update by slot and status
+func SlotStatusKey(slot *pb.SlotStatusEvent) string {
+	return string(binary.BigEndian.AppendUint32(binary.BigEndian.AppendUint64(nil, slot.GetSlot()), uint32(slot.GetStatus())))
+}
+
+// AccountVersionKey identifies one write of an account by pubkey and write_version
+func AccountVersionKey(account *pb.SubscribeUpdateAccountInfo) string {
+	return string(binary.BigEndian.AppendUint64(slices.Clip(account.GetPubkey()), account.GetWriteVersion()))
+}
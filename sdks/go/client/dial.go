@@ -0,0 +1,143 @@
+package thorclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	_ "google.golang.org/grpc/encoding/gzip" // registers the "gzip" compressor
+)
+
+// defaultMaxMsgSize is the message size limit used when Config leaves it unset
+const defaultMaxMsgSize = 100 * 1024 * 1024
+
+// ErrInvalidConfig is wrapped by all errors returned from Config.Validate
+var ErrInvalidConfig = errors.New("invalid config")
+
+// TLSConfig enables TLS for the connection. Without a CAFile the server
+// certificate is verified against the system roots.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of root certificates used instead of the system roots
+	CAFile string
+	// CertFile and KeyFile hold a PEM client certificate for mutual TLS
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the host name used to verify the server certificate
+	ServerName string
+	// InsecureSkipVerify disables server certificate verification. Testing only.
+	InsecureSkipVerify bool
+}
+
+// Validate reports inconsistent or out of range settings
+func (cfg Config) Validate() error {
+	if cfg.ServerAddr == "" && len(cfg.ServerAddrs) == 0 {
+		return fmt.Errorf("%w: server address is required", ErrInvalidConfig)
+	}
+	if cfg.ServerAddr != "" && len(cfg.ServerAddrs) > 0 {
+		return fmt.Errorf("%w: ServerAddr and ServerAddrs are mutually exclusive", ErrInvalidConfig)
+	}
+	if slices.Contains(cfg.ServerAddrs, "") {
+		return fmt.Errorf("%w: empty address in ServerAddrs", ErrInvalidConfig)
+	}
+	if cfg.Token != "" && cfg.Credentials != nil {
+		return fmt.Errorf("%w: Token and Credentials are mutually exclusive", ErrInvalidConfig)
+	}
+	if cfg.DefaultTimeout < 0 || cfg.InitialBackoff < 0 || cfg.MaxBackoff < 0 || cfg.UpdateOverlap < 0 {
+		return fmt.Errorf("%w: timeouts and backoffs must not be negative", ErrInvalidConfig)
+	}
+	if cfg.MaxRecvMsgSize < 0 || cfg.MaxSendMsgSize < 0 {
+		return fmt.Errorf("%w: message size limits must not be negative", ErrInvalidConfig)
+	}
+	if cfg.Compression != "" && encoding.GetCompressor(cfg.Compression) == nil {
+		return fmt.Errorf("%w: unknown compressor %q", ErrInvalidConfig, cfg.Compression)
+	}
+	if ka := cfg.Keepalive; ka != nil && (ka.Time < 0 || ka.Timeout < 0) {
+		return fmt.Errorf("%w: keepalive durations must not be negative", ErrInvalidConfig)
+	}
+	if t := cfg.TLS; t != nil {
+		if (t.CertFile == "") != (t.KeyFile == "") {
+			return fmt.Errorf("%w: TLS client certificate and key must be set together", ErrInvalidConfig)
+		}
+		if t.InsecureSkipVerify && (t.CAFile != "" || t.ServerName != "") {
+			return fmt.Errorf("%w: TLS CA file and server name have no effect with InsecureSkipVerify", ErrInvalidConfig)
+		}
+	}
+	return nil
+}
+
+// dialOptions translates the config into gRPC dial options
+func (cfg Config) dialOptions() ([]grpc.DialOption, error) {
+	creds := insecure.NewCredentials()
+	if cfg.TLS != nil {
+		tlsConfig, err := cfg.TLS.load()
+		if err != nil {
+			return nil, err
+		}
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	maxRecv, maxSend := cfg.MaxRecvMsgSize, cfg.MaxSendMsgSize
+	if maxRecv == 0 {
+		maxRecv = defaultMaxMsgSize
+	}
+	if maxSend == 0 {
+		maxSend = defaultMaxMsgSize
+	}
+	callOpts := []grpc.CallOption{
+		grpc.MaxCallRecvMsgSize(maxRecv),
+		grpc.MaxCallSendMsgSize(maxSend),
+	}
+	if cfg.Compression != "" {
+		callOpts = append(callOpts, grpc.UseCompressor(cfg.Compression))
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultCallOptions(callOpts...),
+	}
+	if cfg.Credentials != nil {
+		opts = append(opts, grpc.WithPerRPCCredentials(cfg.Credentials))
+	}
+	if cfg.Keepalive != nil {
+		opts = append(opts, grpc.WithKeepaliveParams(*cfg.Keepalive))
+	}
+	return append(opts, cfg.DialOptions...), nil
+}
+
+// load reads the configured certificate files
+func (t *TLSConfig) load() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", t.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
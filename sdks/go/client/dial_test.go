@@ -0,0 +1,218 @@
+package thorclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/status"
+)
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   Config
+		valid bool
+	}{
+		{"minimal", Config{ServerAddr: "localhost:50051"}, true},
+		{"no address", Config{}, false},
+		{"negative timeout", Config{ServerAddr: "a", DefaultTimeout: -time.Second}, false},
+		{"negative message size", Config{ServerAddr: "a", MaxRecvMsgSize: -1}, false},
+		{"gzip", Config{ServerAddr: "a", Compression: "gzip"}, true},
+		{"unknown compressor", Config{ServerAddr: "a", Compression: "zstd-x"}, false},
+		{"negative keepalive", Config{ServerAddr: "a", Keepalive: &keepalive.ClientParameters{Time: -1}}, false},
+		{"client certificate without key", Config{ServerAddr: "a", TLS: &TLSConfig{CertFile: "client.pem"}}, false},
+		{"mutual TLS", Config{ServerAddr: "a", TLS: &TLSConfig{CAFile: "ca.pem", CertFile: "client.pem", KeyFile: "client.key"}}, true},
+		{"skip verify with CA", Config{ServerAddr: "a", TLS: &TLSConfig{CAFile: "ca.pem", InsecureSkipVerify: true}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if (err == nil) != tt.valid {
+				t.Fatalf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidConfig) {
+				t.Errorf("Validate() = %v, want ErrInvalidConfig", err)
+			}
+		})
+	}
+}
+
+// testPKI holds the files of a CA with a server and a client certificate
+type testPKI struct {
+	ca                    string
+	serverCert, serverKey string
+	clientCert, clientKey string
+}
+
+// testServerName is the name in the test server certificate
+const testServerName = "thor.test"
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	newKey := func() *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+
+	caKey := newKey()
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issue := func(serial int64, usage x509.ExtKeyUsage, names []string) ([]byte, []byte) {
+		key := newKey()
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "test"},
+			DNSNames:     names,
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return der, keyDER
+	}
+	serverDER, serverKey := issue(2, x509.ExtKeyUsageServerAuth, []string{testServerName})
+	clientDER, clientKey := issue(3, x509.ExtKeyUsageClientAuth, nil)
+
+	return testPKI{
+		ca:         write("ca.pem", "CERTIFICATE", caDER),
+		serverCert: write("server.pem", "CERTIFICATE", serverDER),
+		serverKey:  write("server.key", "PRIVATE KEY", serverKey),
+		clientCert: write("client.pem", "CERTIFICATE", clientDER),
+		clientKey:  write("client.key", "PRIVATE KEY", clientKey),
+	}
+}
+
+// serverTLS returns server credentials for the PKI, requiring a client
+// certificate if mutual is set
+func (p testPKI) serverTLS(t *testing.T, mutual bool) grpc.ServerOption {
+	t.Helper()
+	cert, err := tls.LoadX509KeyPair(p.serverCert, p.serverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{cert}}
+	if mutual {
+		caPEM, err := os.ReadFile(p.ca)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.ClientCAs = x509.NewCertPool()
+		config.ClientCAs.AppendCertsFromPEM(caPEM)
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return grpc.Creds(credentials.NewTLS(config))
+}
+
+func TestDial(t *testing.T) {
+	pki := newTestPKI(t)
+
+	tests := []struct {
+		name string
+		// tls is "" for a plaintext server, "server" or "mutual"
+		tls      string
+		cfg      Config
+		size     int // signature bytes sent by the server
+		wantCode codes.Code
+	}{
+		{name: "plaintext"},
+		{name: "gzip and keepalive", cfg: Config{Compression: "gzip", Keepalive: &keepalive.ClientParameters{Time: time.Minute, Timeout: time.Second}}},
+		{name: "server TLS", tls: "server", cfg: Config{TLS: &TLSConfig{CAFile: pki.ca, ServerName: testServerName}}},
+		{name: "mutual TLS", tls: "mutual", cfg: Config{TLS: &TLSConfig{CAFile: pki.ca, ServerName: testServerName, CertFile: pki.clientCert, KeyFile: pki.clientKey}}},
+		{name: "skip verify", tls: "server", cfg: Config{TLS: &TLSConfig{InsecureSkipVerify: true}}},
+		{name: "unknown CA", tls: "server", cfg: Config{TLS: &TLSConfig{ServerName: testServerName}}, wantCode: codes.Unavailable},
+		{name: "wrong server name", tls: "server", cfg: Config{TLS: &TLSConfig{CAFile: pki.ca}}, wantCode: codes.Unavailable},
+		{name: "missing client certificate", tls: "mutual", cfg: Config{TLS: &TLSConfig{CAFile: pki.ca, ServerName: testServerName}}, wantCode: codes.Unavailable},
+		{name: "plaintext to TLS server", tls: "server", wantCode: codes.Unavailable},
+		{name: "message over MaxRecvMsgSize", cfg: Config{MaxRecvMsgSize: 1024}, size: 4096, wantCode: codes.ResourceExhausted},
+		{name: "custom dial option", cfg: Config{DialOptions: []grpc.DialOption{grpc.WithUserAgent("thor-test")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []grpc.ServerOption
+			if tt.tls != "" {
+				opts = append(opts, pki.serverTLS(t, tt.tls == "mutual"))
+			}
+			size := max(tt.size, 1)
+			addr, _ := startPublisher(t, "", func(ctx context.Context, _ any, send func(*pb.MessageWrapper) error) error {
+				if err := send(txMessage(1, make([]byte, size))); err != nil {
+					return err
+				}
+				return idle(ctx)
+			}, opts...)
+
+			cfg := tt.cfg
+			cfg.ServerAddr, cfg.MaxRetries = addr, -1
+			c, err := NewClient(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			s, err := c.SubscribeToTransactions(ctx)
+			if err == nil {
+				defer s.Close()
+				var tx *pb.TransactionEvent
+				if tx, err = s.Recv(); err == nil && len(tx.Signature) != size {
+					t.Errorf("received a %d byte signature, want %d", len(tx.Signature), size)
+				}
+			}
+			if status.Code(err) != tt.wantCode {
+				t.Errorf("error = %v, want code %v", err, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package thorclient
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+
+	"github.com/mr-tron/base58"
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// LayoutDecoder decodes the data of accounts owned by one program into named
+// fields, enabling field-level diffs
+type LayoutDecoder func(data []byte) (map[string]any, error)
+
+// AccountDiff describes how an account changed between two updates
+type AccountDiff struct {
+	Old, New *pb.SubscribeUpdateAccountInfo
+
+	LamportsDelta     int64
+	OwnerChanged      bool
+	ExecutableChanged bool
+	DataLenDelta      int
+	// DataRanges are the byte ranges of Data that differ, in order. Bytes
+	// past the end of the shorter data count as changed.
+	DataRanges []ByteRange
+	// Fields are the decoded fields that differ, by name, when a layout
+	// decoder is known for the owner and both states decode
+	Fields []FieldChange
+}
+
+// ByteRange is the half-open byte range [Start, End)
+type ByteRange struct {
+	Start, End int
+}
+
+// FieldChange is a decoded field whose value differs
+type FieldChange struct {
+	Name     string
+	Old, New any
+}
+
+// Changed reports whether anything but the write version and slot changed
+func (d AccountDiff) Changed() bool {
+	return d.LamportsDelta != 0 || d.OwnerChanged || d.ExecutableChanged || len(d.DataRanges) > 0
+}
+
+// DiffAccounts compares two states of an account. Layouts, keyed by base58
+// owner program, may be nil.
+func DiffAccounts(old, new *pb.SubscribeUpdateAccountInfo, layouts map[string]LayoutDecoder) AccountDiff {
+	d := AccountDiff{
+		Old:               old,
+		New:               new,
+		LamportsDelta:     int64(new.GetLamports() - old.GetLamports()),
+		OwnerChanged:      string(old.GetOwner()) != string(new.GetOwner()),
+		ExecutableChanged: old.GetExecutable() != new.GetExecutable(),
+		DataLenDelta:      len(new.GetData()) - len(old.GetData()),
+		DataRanges:        changedRanges(old.GetData(), new.GetData()),
+	}
+	if decode := layouts[base58.Encode(new.GetOwner())]; decode != nil && !d.OwnerChanged && len(d.DataRanges) > 0 {
+		d.Fields = diffFields(decode, old.GetData(), new.GetData())
+	}
+	return d
+}
+
+// changedRanges returns the ranges where a and b differ
+func changedRanges(a, b []byte) []ByteRange {
+	var ranges []ByteRange
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		start := i
+		for i < n && a[i] != b[i] {
+			i++
+		}
+		ranges = append(ranges, ByteRange{Start: start, End: i})
+	}
+	if tail := max(len(a), len(b)); tail > n {
+		if len(ranges) > 0 && ranges[len(ranges)-1].End == n {
+			ranges[len(ranges)-1].End = tail
+		} else {
+			ranges = append(ranges, ByteRange{Start: n, End: tail})
+		}
+	}
+	return ranges
+}
+
+// diffFields decodes both states and returns the fields that differ
+func diffFields(decode LayoutDecoder, old, new []byte) []FieldChange {
+	before, err := decode(old)
+	if err != nil {
+		return nil
+	}
+	after, err := decode(new)
+	if err != nil {
+		return nil
+	}
+
+	names := make(map[string]struct{}, len(after))
+	for name := range before {
+		names[name] = struct{}{}
+	}
+	for name := range after {
+		names[name] = struct{}{}
+	}
+
+	var changes []FieldChange
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		if !reflect.DeepEqual(before[name], after[name]) {
+			changes = append(changes, FieldChange{Name: name, Old: before[name], New: after[name]})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,203 @@
+package thorclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+func TestChangedRanges(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []byte
+		want []ByteRange
+	}{
+		{"equal", []byte{1, 2, 3}, []byte{1, 2, 3}, nil},
+		{"both empty", nil, nil, nil},
+		{"one byte", []byte{1, 2, 3}, []byte{1, 9, 3}, []ByteRange{{1, 2}}},
+		{"adjacent bytes", []byte{1, 2, 3, 4}, []byte{1, 8, 9, 4}, []ByteRange{{1, 3}}},
+		{"separate ranges", []byte{1, 2, 3, 4, 5}, []byte{9, 2, 3, 9, 9}, []ByteRange{{0, 1}, {3, 5}}},
+		{"grown", []byte{1, 2}, []byte{1, 2, 3, 4}, []ByteRange{{2, 4}}},
+		{"shrunk", []byte{1, 2, 3, 4}, []byte{1, 2}, []ByteRange{{2, 4}}},
+		{"from empty", nil, []byte{1, 2}, []ByteRange{{0, 2}}},
+		{"change runs into growth", []byte{1, 2, 3}, []byte{1, 9, 9, 4}, []ByteRange{{1, 4}}},
+		{"change apart from growth", []byte{1, 2, 3}, []byte{9, 2, 3, 4}, []ByteRange{{0, 1}, {3, 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changedRanges(tt.a, tt.b); !slices.Equal(got, tt.want) {
+				t.Errorf("changedRanges(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// balanceLayout decodes test accounts holding a little-endian u64 amount and
+// a flag byte
+func balanceLayout(data []byte) (map[string]any, error) {
+	if len(data) != 9 {
+		return nil, errors.New("want 9 bytes")
+	}
+	return map[string]any{
+		"amount": binary.LittleEndian.Uint64(data),
+		"frozen": data[8] != 0,
+	}, nil
+}
+
+// balanceData encodes a balanceLayout account
+func balanceData(amount uint64, frozen bool) []byte {
+	data := binary.LittleEndian.AppendUint64(nil, amount)
+	if frozen {
+		return append(data, 1)
+	}
+	return append(data, 0)
+}
+
+func TestDiffAccounts(t *testing.T) {
+	program := bytes.Repeat([]byte{7}, 32)
+	layouts := map[string]LayoutDecoder{testWallet(7): balanceLayout}
+	account := func(lamports uint64, owner []byte, executable bool, data []byte) *pb.SubscribeUpdateAccountInfo {
+		return &pb.SubscribeUpdateAccountInfo{Lamports: lamports, Owner: owner, Executable: executable, Data: data}
+	}
+	other := bytes.Repeat([]byte{8}, 32)
+
+	tests := []struct {
+		name     string
+		old, new *pb.SubscribeUpdateAccountInfo
+		want     AccountDiff // Old and New are filled in
+		changed  bool
+	}{
+		{
+			name: "unchanged",
+			old:  account(100, program, false, balanceData(5, false)),
+			new:  account(100, program, false, balanceData(5, false)),
+		},
+		{
+			name:    "lamports up",
+			old:     account(100, program, false, nil),
+			new:     account(150, program, false, nil),
+			want:    AccountDiff{LamportsDelta: 50},
+			changed: true,
+		},
+		{
+			name:    "lamports down",
+			old:     account(150, program, false, nil),
+			new:     account(100, program, false, nil),
+			want:    AccountDiff{LamportsDelta: -50},
+			changed: true,
+		},
+		{
+			name:    "owner and executable",
+			old:     account(100, other, false, nil),
+			new:     account(100, program, true, nil),
+			want:    AccountDiff{OwnerChanged: true, ExecutableChanged: true},
+			changed: true,
+		},
+		{
+			name: "decoded fields",
+			old:  account(100, program, false, balanceData(5, false)),
+			new:  account(100, program, false, balanceData(6, true)),
+			want: AccountDiff{
+				DataRanges: []ByteRange{{0, 1}, {8, 9}},
+				Fields:     []FieldChange{{"amount", uint64(5), uint64(6)}, {"frozen", false, true}},
+			},
+			changed: true,
+		},
+		{
+			name:    "no layout for the owner",
+			old:     account(100, other, false, balanceData(5, false)),
+			new:     account(100, other, false, balanceData(6, false)),
+			want:    AccountDiff{DataRanges: []ByteRange{{0, 1}}},
+			changed: true,
+		},
+		{
+			name:    "undecodable data",
+			old:     account(100, program, false, balanceData(5, false)),
+			new:     account(100, program, false, append(balanceData(5, false), 1)),
+			want:    AccountDiff{DataLenDelta: 1, DataRanges: []ByteRange{{9, 10}}},
+			changed: true,
+		},
+		{
+			// Data laid out for another program is not compared field by field
+			name:    "owner changed with data",
+			old:     account(100, other, false, balanceData(5, false)),
+			new:     account(100, program, false, balanceData(6, false)),
+			want:    AccountDiff{OwnerChanged: true, DataRanges: []ByteRange{{0, 1}}},
+			changed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.want
+			want.Old, want.New = tt.old, tt.new
+			got := DiffAccounts(tt.old, tt.new, layouts)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("DiffAccounts() = %+v, want %+v", got, want)
+			}
+			if got.Changed() != tt.changed {
+				t.Errorf("Changed() = %v, want %v", got.Changed(), tt.changed)
+			}
+		})
+	}
+}
+
+func TestAccountCacheDiffs(t *testing.T) {
+	var mu sync.Mutex
+	var diffs []AccountDiff
+	c := newTestCache(t, AccountCacheOptions{
+		OnDiff: func(d AccountDiff) {
+			mu.Lock()
+			diffs = append(diffs, d)
+			mu.Unlock()
+		},
+		Layouts: map[string]LayoutDecoder{testWallet(9): balanceLayout},
+	})
+
+	update := func(writeVersion, amount uint64) *pb.SubscribeUpdateAccountInfo {
+		account := cachedAccount(1, 9, writeVersion, 100, 0)
+		account.Data = balanceData(amount, false)
+		return account
+	}
+	c.Apply(update(1, 10)) // first state, nothing to diff against
+	c.Apply(update(3, 30))
+	c.Apply(update(2, 20)) // stale, not applied
+
+	var wg sync.WaitGroup
+	for v := uint64(4); v < 20; v++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Apply(update(v, v*10))
+		}()
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	// Racing updates older than one already applied are dropped, but the
+	// newest is always applied
+	if len(diffs) < 2 || diffs[len(diffs)-1].New.WriteVersion != 19 {
+		t.Fatalf("%d diffs, want at least 2 ending at write_version 19", len(diffs))
+	}
+	if diffs[0].Old.WriteVersion != 1 || diffs[0].New.WriteVersion != 3 {
+		t.Fatalf("first diff %+v, want write_version 1 to 3", diffs[0])
+	}
+	if want := []FieldChange{{"amount", uint64(10), uint64(30)}}; !reflect.DeepEqual(diffs[0].Fields, want) {
+		t.Errorf("first diff fields %v, want %v", diffs[0].Fields, want)
+	}
+	// Diffs chain in apply order, whatever order the updates raced in
+	for i := 1; i < len(diffs); i++ {
+		if diffs[i].Old != diffs[i-1].New || diffs[i].New.WriteVersion <= diffs[i].Old.WriteVersion {
+			t.Errorf("diff %d starts from write_version %d, want %d", i, diffs[i].Old.WriteVersion, diffs[i-1].New.WriteVersion)
+		}
+	}
+	if state, _ := c.Get(testWallet(1)); diffs[len(diffs)-1].New != state.Account {
+		t.Error("last diff does not end at the cached state")
+	}
+}
@@ -0,0 +1,211 @@
+package thorclient
+
+import (
+	"container/list"
+	"context"
+	"hash/fnv"
+	"runtime"
+	"sync"
+
+	"github.com/mr-tron/base58"
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// KeyFunc returns the ordering key of a message. Messages with equal keys are
+// handled one at a time in arrival order; an empty key may go to any shard.
+type KeyFunc[T any] func(T) string
+
+// DispatchOptions configures Dispatch
+type DispatchOptions struct {
+	// Shards is the number of goroutines running the handler (default runtime.NumCPU())
+	Shards int
+	// ShardQueueSize bounds the messages waiting per shard (default 256). The
+	// stream is not read while the target shard's queue is full.
+	ShardQueueSize int
+	// OnError is called with errors returned by the handler
+	OnError func(error)
+}
+
+// Dispatch processes stream with handler on a fixed set of shards selected by
+// key, so messages sharing a key are processed sequentially while different
+// keys run concurrently. It returns immediately; see Consume for the
+// lifetime of ctx and the returned Consumer.
+func Dispatch[T any](ctx context.Context, stream Receiver[T], key KeyFunc[T], handler func(context.Context, T) error, opts DispatchOptions) *Consumer {
+	if opts.Shards <= 0 {
+		opts.Shards = runtime.NumCPU()
+	}
+	if opts.ShardQueueSize <= 0 {
+		opts.ShardQueueSize = 256
+	}
+
+	shards := make([]chan T, opts.Shards)
+	for i := range shards {
+		shards[i] = make(chan T, opts.ShardQueueSize)
+	}
+	c := &Consumer{
+		depth: func() int {
+			depth := 0
+			for _, shard := range shards {
+				depth += len(shard)
+			}
+			return depth
+		},
+		capacity: opts.Shards * opts.ShardQueueSize,
+		done:     make(chan struct{}),
+	}
+
+	var workers sync.WaitGroup
+	for _, shard := range shards {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			work(ctx, c, shard, handler, opts.OnError)
+		}()
+	}
+
+	go func() {
+		c.err = route(ctx, c, stream, key, shards)
+		for _, shard := range shards {
+			close(shard)
+		}
+		workers.Wait()
+		close(c.done)
+	}()
+
+	return c
+}
+
+// route reads stream and sends each message to the shard owning its key
+func route[T any](ctx context.Context, c *Consumer, stream Receiver[T], key KeyFunc[T], shards []chan T) error {
+	next := 0
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			if IsStreamDone(err) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		c.received.Add(1)
+
+		var shard int
+		if k := key(msg); k != "" {
+			h := fnv.New32a()
+			h.Write([]byte(k))
+			shard = int(h.Sum32() % uint32(len(shards)))
+		} else {
+			shard = next % len(shards)
+			next++
+		}
+
+		select {
+		case shards[shard] <- msg:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// latestAccountsSize is the number of recently updated pubkeys whose last
+// write_version DispatchAccounts remembers
+const latestAccountsSize = 1 << 16
+
+// DispatchAccounts dispatches account updates by pubkey. Updates whose
+// write_version is not newer than one already seen for the same pubkey are
+// skipped, so handlers observe each account's writes in order. Only the
+// most recently updated 65536 pubkeys are remembered.
+func DispatchAccounts(ctx context.Context, stream Receiver[*pb.SubscribeUpdateAccountInfo], handler func(context.Context, *pb.SubscribeUpdateAccountInfo) error, opts DispatchOptions) *Consumer {
+	latest := &latestAccounts{
+		stream: stream,
+		seen:   make(map[string]*list.Element),
+		lru:    list.New(),
+	}
+	return Dispatch(ctx, latest, AccountKey, handler, opts)
+}
+
+// latestAccounts drops account updates older than the last one seen per
+// pubkey, forgetting the least recently updated pubkeys beyond
+// latestAccountsSize
+type latestAccounts struct {
+	stream Receiver[*pb.SubscribeUpdateAccountInfo]
+	seen   map[string]*list.Element
+	lru    *list.List
+}
+
+type accountVersion struct {
+	key     string
+	version uint64
+}
+
+func (l *latestAccounts) Recv() (*pb.SubscribeUpdateAccountInfo, error) {
+	for {
+		account, err := l.stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+		key := string(account.Pubkey)
+		if elem, ok := l.seen[key]; ok {
+			last := elem.Value.(*accountVersion)
+			if account.WriteVersion <= last.version {
+				continue
+			}
+			last.version = account.WriteVersion
+			l.lru.MoveToFront(elem)
+			return account, nil
+		}
+
+		l.seen[key] = l.lru.PushFront(&accountVersion{key: key, version: account.WriteVersion})
+		if l.lru.Len() > latestAccountsSize {
+			oldest := l.lru.Remove(l.lru.Back()).(*accountVersion)
+			delete(l.seen, oldest.key)
+		}
+		return account, nil
+	}
+}
+
+// AccountKey keys account updates by pubkey
+func AccountKey(account *pb.SubscribeUpdateAccountInfo) string {
+	return string(account.GetPubkey())
+}
+
+// SignerKey keys transactions by fee payer, the first account key
+func SignerKey(tx *pb.TransactionEvent) string {
+	keys := tx.GetTransaction().GetMessage().GetAccountKeys()
+	if len(keys) == 0 {
+		return ""
+	}
+	return string(keys[0])
+}
+
+// ProgramKey keys transactions by the program of their first instruction
+func ProgramKey(tx *pb.TransactionEvent) string {
+	msg := tx.GetTransaction().GetMessage()
+	if len(msg.GetInstructions()) == 0 {
+		return ""
+	}
+	index := int(msg.Instructions[0].ProgramIdIndex)
+	if index >= len(msg.AccountKeys) {
+		return ""
+	}
+	return string(msg.AccountKeys[index])
+}
+
+// WalletKey keys transactions by the first of wallets found among their
+// static account keys. Invalid addresses are ignored.
+func WalletKey(wallets []string) KeyFunc[*pb.TransactionEvent] {
+	watched := make(map[string]bool, len(wallets))
+	for _, wallet := range wallets {
+		if key, err := base58.Decode(wallet); err == nil {
+			watched[string(key)] = true
+		}
+	}
+
+	return func(tx *pb.TransactionEvent) string {
+		for _, key := range tx.GetTransaction().GetMessage().GetAccountKeys() {
+			if watched[string(key)] {
+				return string(key)
+			}
+		}
+		return ""
+	}
+}
@@ -0,0 +1,177 @@
+package thorclient
+
+import (
+	"bytes"
+	"context"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// keyed is a message with an ordering key and its position among the
+// messages of that key
+type keyed struct {
+	key string
+	seq int
+}
+
+// shardOf returns the shard Dispatch routes key to
+func shardOf(key string, shards int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(shards))
+}
+
+func TestDispatchOrdering(t *testing.T) {
+	const keys, perKey = 16, 50
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source := make(chan keyed, keys*perKey)
+	for seq := range perKey {
+		for k := range keys {
+			source <- keyed{string(rune('a' + k)), seq}
+		}
+	}
+	close(source)
+
+	var mu sync.Mutex
+	handled := make(map[string][]int)
+	c := Dispatch(ctx, chanReceiver[keyed]{ctx, source}, func(m keyed) string { return m.key }, func(ctx context.Context, m keyed) error {
+		if m.seq%7 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		mu.Lock()
+		handled[m.key] = append(handled[m.key], m.seq)
+		mu.Unlock()
+		return nil
+	}, DispatchOptions{Shards: 4, ShardQueueSize: 8})
+
+	if err := c.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if len(handled) != keys {
+		t.Fatalf("handled %d keys, want %d", len(handled), keys)
+	}
+	for key, seqs := range handled {
+		if len(seqs) != perKey || !slices.IsSorted(seqs) {
+			t.Errorf("key %q handled in order %v, want 0 to %d", key, seqs, perKey-1)
+		}
+	}
+	if stats := c.Stats(); stats.Received != keys*perKey || stats.Processed != keys*perKey || stats.QueueCapacity != 32 {
+		t.Errorf("stats %+v, want %d received and processed, capacity 32", stats, keys*perKey)
+	}
+}
+
+func TestDispatchConcurrentKeys(t *testing.T) {
+	// Find a key on another shard than the blocked one
+	blocked, other := "blocked", ""
+	for k := 'a'; other == ""; k++ {
+		if shardOf(string(k), 2) != shardOf(blocked, 2) {
+			other = string(k)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source := make(chan keyed)
+	release, done := make(chan struct{}), make(chan struct{})
+	c := Dispatch(ctx, chanReceiver[keyed]{ctx, source}, func(m keyed) string { return m.key }, func(ctx context.Context, m keyed) error {
+		if m.key == blocked {
+			<-release
+		} else {
+			close(done)
+		}
+		return nil
+	}, DispatchOptions{Shards: 2})
+
+	source <- keyed{blocked, 0}
+	source <- keyed{other, 0}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("a blocked key held up a key on another shard")
+	}
+	close(release)
+	close(source)
+	if err := c.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+}
+
+func TestDispatchAccounts(t *testing.T) {
+	account := func(b byte, writeVersion uint64) *pb.SubscribeUpdateAccountInfo {
+		return accountMessage(b, writeVersion).GetAccountUpdate()
+	}
+	updates := []*pb.SubscribeUpdateAccountInfo{
+		account(1, 1), account(2, 5), account(1, 3), account(1, 2), account(2, 5), account(1, 4), account(2, 1), account(2, 6),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	source := make(chan *pb.SubscribeUpdateAccountInfo, len(updates))
+	for _, u := range updates {
+		source <- u
+	}
+	close(source)
+
+	var mu sync.Mutex
+	handled := make(map[byte][]uint64)
+	c := DispatchAccounts(ctx, chanReceiver[*pb.SubscribeUpdateAccountInfo]{ctx, source}, func(ctx context.Context, u *pb.SubscribeUpdateAccountInfo) error {
+		mu.Lock()
+		handled[u.Pubkey[0]] = append(handled[u.Pubkey[0]], u.WriteVersion)
+		mu.Unlock()
+		return nil
+	}, DispatchOptions{Shards: 4})
+
+	if err := c.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	want := map[byte][]uint64{1: {1, 3, 4}, 2: {5, 6}}
+	for b, versions := range want {
+		if !slices.Equal(handled[b], versions) {
+			t.Errorf("account %d handled versions %v, want %v", b, handled[b], versions)
+		}
+	}
+	if stats := c.Stats(); stats.Received != 5 {
+		t.Errorf("received %d updates, want the 5 newer ones", stats.Received)
+	}
+}
+
+func TestKeyFuncs(t *testing.T) {
+	signer, program, wallet := []byte("signer"), []byte("program"), []byte("wallet")
+	tx := func(keys [][]byte, instructions ...uint32) *pb.TransactionEvent {
+		msg := &pb.Message{AccountKeys: keys}
+		for _, index := range instructions {
+			msg.Instructions = append(msg.Instructions, &pb.CompiledInstruction{ProgramIdIndex: index})
+		}
+		return &pb.TransactionEvent{Transaction: &pb.SanitizedTransaction{Message: msg}}
+	}
+	walletKey := WalletKey([]string{"not base58!", testWallet(9)})
+	watched := bytes.Repeat([]byte{9}, 32)
+
+	tests := []struct {
+		name string
+		key  KeyFunc[*pb.TransactionEvent]
+		tx   *pb.TransactionEvent
+		want string
+	}{
+		{"signer", SignerKey, tx([][]byte{signer, program}, 1), "signer"},
+		{"signer without keys", SignerKey, &pb.TransactionEvent{}, ""},
+		{"program", ProgramKey, tx([][]byte{signer, program}, 1, 0), "program"},
+		{"program without instructions", ProgramKey, tx([][]byte{signer, program}), ""},
+		{"program index out of range", ProgramKey, tx([][]byte{signer}, 3), ""},
+		{"watched wallet", walletKey, tx([][]byte{signer, wallet, watched}), string(watched)},
+		{"no watched wallet", walletKey, tx([][]byte{signer, wallet}), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.key(tt.tx); got != tt.want {
+				t.Errorf("key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorKind groups errors by how callers should react to them
+type ErrorKind int
+
+const (
+	// KindFatal errors will not go away by retrying
+	KindFatal ErrorKind = iota
+	// KindAuth errors require a new or corrected token
+	KindAuth
+	// KindLimit errors mean a subscription quota was exceeded
+	KindLimit
+	// KindRequest errors mean the subscribe request was rejected as invalid
+	KindRequest
+	// KindTransient errors are connection failures that a reconnect may fix
+	KindTransient
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindFatal:
+		return "fatal"
+	case KindAuth:
+		return "auth"
+	case KindLimit:
+		return "limit"
+	case KindRequest:
+		return "request"
+	case KindTransient:
+		return "transient"
+	default:
+		return fmt.Sprintf("ErrorKind(%d)", int(k))
+	}
+}
+
+// Error is a classified ThorStreamer error. It wraps the original error, so
+// status.FromError still works on it.
+type Error struct {
+	Kind ErrorKind
+	// Code is the documented server error code, e.g. "TOO_MANY_WALLET_ADDRESSES",
+	// or empty if none was reported
+	Code string
+	// Status is the gRPC status code of the underlying error
+	Status codes.Code
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Code
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches errors carrying the same server error code, so the sentinels
+// below can be used with errors.Is
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code != "" && t.Code == e.Code
+}
+
+// Server error codes documented in docs/error-handling.md
+var (
+	ErrUnauthenticated = &Error{Kind: KindAuth, Code: "UNAUTHENTICATED", Status: codes.Unauthenticated}
+	ErrTokenExpired    = &Error{Kind: KindAuth, Code: "TOKEN_EXPIRED", Status: codes.Unauthenticated}
+	ErrInvalidToken    = &Error{Kind: KindAuth, Code: "INVALID_TOKEN", Status: codes.Unauthenticated}
+
+	ErrSubscriptionLimitReached            = &Error{Kind: KindLimit, Code: "SUBSCRIPTION_LIMIT_REACHED", Status: codes.ResourceExhausted}
+	ErrTransactionSubscriptionLimitReached = &Error{Kind: KindLimit, Code: "TRANSACTION_SUBSCRIPTION_LIMIT_REACHED", Status: codes.ResourceExhausted}
+	ErrAccountSubscriptionLimitReached     = &Error{Kind: KindLimit, Code: "ACCOUNT_SUBSCRIPTION_LIMIT_REACHED", Status: codes.ResourceExhausted}
+	ErrSlotSubscriptionLimitReached        = &Error{Kind: KindLimit, Code: "SLOT_SUBSCRIPTION_LIMIT_REACHED", Status: codes.ResourceExhausted}
+	ErrWalletSubscriptionLimitReached      = &Error{Kind: KindLimit, Code: "WALLET_SUBSCRIPTION_LIMIT_REACHED", Status: codes.ResourceExhausted}
+
+	ErrConnectionError   = &Error{Kind: KindTransient, Code: "CONNECTION_ERROR", Status: codes.Unavailable}
+	ErrConnectionClosed  = &Error{Kind: KindTransient, Code: "CONNECTION_CLOSED", Status: codes.Unavailable}
+	ErrConnectionTimeout = &Error{Kind: KindTransient, Code: "CONNECTION_TIMEOUT", Status: codes.DeadlineExceeded}
+	ErrStreamClosed      = &Error{Kind: KindTransient, Code: "STREAM_CLOSED", Status: codes.Unavailable}
+
+	ErrInvalidRequest        = &Error{Kind: KindRequest, Code: "INVALID_REQUEST", Status: codes.InvalidArgument}
+	ErrInvalidWalletAddress  = &Error{Kind: KindRequest, Code: "INVALID_WALLET_ADDRESS", Status: codes.InvalidArgument}
+	ErrInvalidAccountAddress = &Error{Kind: KindRequest, Code: "INVALID_ACCOUNT_ADDRESS", Status: codes.InvalidArgument}
+	ErrEmptyWalletList       = &Error{Kind: KindRequest, Code: "EMPTY_WALLET_LIST", Status: codes.InvalidArgument}
+	ErrEmptyAccountList      = &Error{Kind: KindRequest, Code: "EMPTY_ACCOUNT_LIST", Status: codes.InvalidArgument}
+	// The address count limits reject one request rather than a quota of
+	// open streams, so splitting the request fixes them
+	ErrTooManyWalletAddresses  = &Error{Kind: KindRequest, Code: "TOO_MANY_WALLET_ADDRESSES", Status: codes.InvalidArgument}
+	ErrTooManyAccountAddresses = &Error{Kind: KindRequest, Code: "TOO_MANY_ACCOUNT_ADDRESSES", Status: codes.InvalidArgument}
+)
+
+// ErrStreamStalled is reported by the client when a stream delivers nothing
+// for longer than its stall timeout, see Watchdog
+var ErrStreamStalled = &Error{Kind: KindTransient, Code: "STREAM_STALLED", Status: codes.DeadlineExceeded}
+
+// knownCodes indexes the sentinels above by code
+var knownCodes = map[string]*Error{}
+
+func init() {
+	for _, e := range []*Error{
+		ErrUnauthenticated, ErrTokenExpired, ErrInvalidToken,
+		ErrSubscriptionLimitReached, ErrTransactionSubscriptionLimitReached,
+		ErrAccountSubscriptionLimitReached, ErrSlotSubscriptionLimitReached,
+		ErrWalletSubscriptionLimitReached, ErrTooManyWalletAddresses, ErrTooManyAccountAddresses,
+		ErrConnectionError, ErrConnectionClosed, ErrConnectionTimeout, ErrStreamClosed,
+		ErrInvalidRequest, ErrInvalidWalletAddress, ErrInvalidAccountAddress,
+		ErrEmptyWalletList, ErrEmptyAccountList,
+	} {
+		knownCodes[e.Code] = e
+	}
+}
+
+// Classify returns err as an *Error, deriving its kind and server error code
+// from the gRPC status. It returns nil for a nil error.
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	return classify(err)
+}
+
+// classify returns err, which must not be nil, as an *Error
+func classify(err error) *Error {
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified
+	}
+
+	switch {
+	case errors.Is(err, io.EOF):
+		return &Error{Kind: KindTransient, Code: ErrStreamClosed.Code, Status: codes.Unavailable, Err: err}
+	case errors.Is(err, context.Canceled):
+		return &Error{Kind: KindFatal, Status: codes.Canceled, Err: err}
+	case errors.Is(err, context.DeadlineExceeded):
+		// Unlike a DeadlineExceeded status from the server or transport, a
+		// bare context error means the caller's own context expired, which
+		// no retry with that context can fix
+		return &Error{Kind: KindFatal, Status: codes.DeadlineExceeded, Err: err}
+	}
+
+	s, ok := status.FromError(err)
+	if !ok {
+		return &Error{Kind: KindFatal, Status: codes.Unknown, Err: err}
+	}
+	if known := serverCode(s); known != nil {
+		return &Error{Kind: known.Kind, Code: known.Code, Status: s.Code(), Err: err}
+	}
+	return &Error{Kind: kindOf(s.Code()), Status: s.Code(), Err: err}
+}
+
+// serverCode finds a documented error code in the status details or message
+func serverCode(s *status.Status) *Error {
+	for _, detail := range s.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			if known := knownCodes[info.Reason]; known != nil {
+				return known
+			}
+		}
+	}
+
+	words := strings.FieldsFunc(s.Message(), func(r rune) bool {
+		return !(r >= 'A' && r <= 'Z' || r == '_')
+	})
+	for _, word := range words {
+		if known := knownCodes[word]; known != nil {
+			return known
+		}
+	}
+	return nil
+}
+
+// kindOf classifies gRPC codes that come without a documented server code
+func kindOf(code codes.Code) ErrorKind {
+	switch code {
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return KindAuth
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return KindRequest
+	case codes.ResourceExhausted:
+		// The server reports subscription quotas with this code, so an
+		// unrecognized quota message must not be retried forever
+		return KindLimit
+	case codes.Unavailable, codes.Aborted, codes.Internal, codes.DeadlineExceeded:
+		return KindTransient
+	default:
+		return KindFatal
+	}
+}
+
+// IsRetryable reports whether err is transient and worth reconnecting after
+func IsRetryable(err error) bool {
+	return err != nil && classify(err).Kind == KindTransient
+}
+
+// IsAuthError reports whether err was caused by a missing, invalid or expired token
+func IsAuthError(err error) bool {
+	return err != nil && classify(err).Kind == KindAuth
+}
+
+// IsLimitError reports whether err was caused by exceeding a subscription quota
+func IsLimitError(err error) bool {
+	return err != nil && classify(err).Kind == KindLimit
+}
+
+// IsRequestError reports whether the server rejected the subscribe request
+func IsRequestError(err error) bool {
+	return err != nil && classify(err).Kind == KindRequest
+}
+
+// IsFatal reports whether err cannot be resolved by retrying or reconfiguring
+func IsFatal(err error) bool {
+	return err != nil && classify(err).Kind == KindFatal
+}
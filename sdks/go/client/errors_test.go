@@ -0,0 +1,101 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want ErrorKind
+	}{
+		{codes.Unauthenticated, KindAuth},
+		{codes.PermissionDenied, KindAuth},
+		{codes.InvalidArgument, KindRequest},
+		{codes.OutOfRange, KindRequest},
+		{codes.FailedPrecondition, KindRequest},
+		{codes.ResourceExhausted, KindLimit},
+		{codes.Unavailable, KindTransient},
+		{codes.Aborted, KindTransient},
+		{codes.Internal, KindTransient},
+		{codes.DeadlineExceeded, KindTransient},
+		{codes.Unknown, KindFatal},
+		{codes.Unimplemented, KindFatal},
+		{codes.NotFound, KindFatal},
+	}
+	for _, tt := range tests {
+		if got := kindOf(tt.code); got != tt.want {
+			t.Errorf("kindOf(%v) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	withReason := func(code codes.Code, reason string) error {
+		s, err := status.New(code, "rejected").WithDetails(&errdetails.ErrorInfo{Reason: reason})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s.Err()
+	}
+
+	tests := []struct {
+		name     string
+		err      error
+		kind     ErrorKind
+		code     string
+		status   codes.Code
+		sentinel error
+	}{
+		{"io.EOF", io.EOF, KindTransient, "STREAM_CLOSED", codes.Unavailable, ErrStreamClosed},
+		{"canceled", context.Canceled, KindFatal, "", codes.Canceled, nil},
+		{"context deadline", fmt.Errorf("dial: %w", context.DeadlineExceeded), KindFatal, "", codes.DeadlineExceeded, nil},
+		{"plain error", errors.New("boom"), KindFatal, "", codes.Unknown, nil},
+		{"status without code", status.Error(codes.Unavailable, "connection reset"), KindTransient, "", codes.Unavailable, nil},
+		{"deadline status", status.Error(codes.DeadlineExceeded, "timeout"), KindTransient, "", codes.DeadlineExceeded, nil},
+		{"unrecognized quota", status.Error(codes.ResourceExhausted, "quota exceeded"), KindLimit, "", codes.ResourceExhausted, nil},
+		{"code in message", status.Error(codes.Unauthenticated, "TOKEN_EXPIRED: renew it"), KindAuth, "TOKEN_EXPIRED", codes.Unauthenticated, ErrTokenExpired},
+		{"code in details", withReason(codes.ResourceExhausted, "WALLET_SUBSCRIPTION_LIMIT_REACHED"), KindLimit, "WALLET_SUBSCRIPTION_LIMIT_REACHED", codes.ResourceExhausted, ErrWalletSubscriptionLimitReached},
+		{"address count", status.Error(codes.InvalidArgument, "TOO_MANY_WALLET_ADDRESSES"), KindRequest, "TOO_MANY_WALLET_ADDRESSES", codes.InvalidArgument, ErrTooManyWalletAddresses},
+		{"classified", localError(ErrEmptyAccountList, "no accounts"), KindRequest, "EMPTY_ACCOUNT_LIST", codes.InvalidArgument, ErrEmptyAccountList},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e *Error
+			if !errors.As(Classify(tt.err), &e) {
+				t.Fatalf("Classify(%v) is not an *Error", tt.err)
+			}
+			if e.Kind != tt.kind || e.Code != tt.code || e.Status != tt.status {
+				t.Errorf("Classify(%v) = {%v %q %v}, want {%v %q %v}", tt.err, e.Kind, e.Code, e.Status, tt.kind, tt.code, tt.status)
+			}
+			if tt.sentinel != nil && !errors.Is(e, tt.sentinel) {
+				t.Errorf("Classify(%v) does not match %v", tt.err, tt.sentinel)
+			}
+			if !errors.Is(e, tt.err) {
+				t.Errorf("Classify(%v) does not wrap the original error", tt.err)
+			}
+		})
+	}
+}
+
+func TestClassifyNil(t *testing.T) {
+	if err := Classify(nil); err != nil {
+		t.Errorf("Classify(nil) = %#v, want nil", err)
+	}
+	for name, is := range map[string]func(error) bool{
+		"IsRetryable": IsRetryable, "IsAuthError": IsAuthError, "IsLimitError": IsLimitError,
+		"IsRequestError": IsRequestError, "IsFatal": IsFatal,
+	} {
+		if is(nil) {
+			t.Errorf("%s(nil) = true", name)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package thorclient
+
+import (
+	"errors"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/status"
+)
+
+// errFailback cancels streams on a standby endpoint once the primary is
+// healthy again
+var errFailback = errors.New("failing back to the primary endpoint")
+
+// endpoint is one of the servers a client can stream from
+type endpoint struct {
+	addr        string
+	conn        *grpc.ClientConn
+	eventClient pb.EventPublisherClient
+	thorClient  pb.ThorStreamerClient
+}
+
+func newEndpoint(addr string, conn *grpc.ClientConn) *endpoint {
+	return &endpoint{
+		addr:        addr,
+		conn:        conn,
+		eventClient: pb.NewEventPublisherClient(conn),
+		thorClient:  pb.NewThorStreamerClient(conn),
+	}
+}
+
+// Endpoint returns the address new and reconnecting streams use
+func (c *Client) Endpoint() string {
+	_, ep := c.activeEndpoint()
+	return ep.addr
+}
+
+func (c *Client) activeEndpoint() (int, *endpoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.active, c.endpoints[c.active]
+}
+
+// shouldFailover reports whether err means the endpoint itself is unhealthy
+func shouldFailover(err error) bool {
+	return status.Code(err) == codes.Unavailable || errors.Is(err, ErrStreamStalled)
+}
+
+// failover moves the client from the endpoint at index from to the next one.
+// It reports false if there is no other endpoint; if another stream already
+// moved the client away from from, it reports true without moving again.
+func (c *Client) failover(kind SubscriptionType, from int, cause error) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.endpoints) < 2 {
+		return false
+	}
+	if c.active != from {
+		return true
+	}
+
+	c.active = (from + 1) % len(c.endpoints)
+	c.emit(StatusEvent{Type: StatusFailover, Stream: kind, Endpoint: c.endpoints[c.active].addr, Err: cause})
+	if c.active != 0 && c.failbackAfter > 0 && !c.probing {
+		c.probing = true
+		go c.probePrimary()
+	}
+	return true
+}
+
+// probePrimary waits for the primary endpoint to stay connected for
+// failbackAfter, then moves all streams back to it
+func (c *Client) probePrimary() {
+	primary := c.endpoints[0]
+	ticker := time.NewTicker(max(min(c.failbackAfter/4, time.Second), 10*time.Millisecond))
+	defer ticker.Stop()
+
+	var healthySince time.Time
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		c.mu.Lock()
+		if c.active == 0 {
+			// Failed over all the way round
+			c.probing = false
+			c.mu.Unlock()
+			return
+		}
+		c.mu.Unlock()
+
+		primary.conn.Connect()
+		if primary.conn.GetState() != connectivity.Ready {
+			healthySince = time.Time{}
+			continue
+		}
+		if healthySince.IsZero() {
+			healthySince = time.Now()
+		}
+		if time.Since(healthySince) >= c.failbackAfter {
+			c.failback()
+			return
+		}
+	}
+}
+
+// failback makes the primary endpoint active and reconnects every stream
+// attached to another endpoint
+func (c *Client) failback() {
+	c.mu.Lock()
+	c.active, c.probing = 0, false
+	streams := make([]*resumableStream, 0, len(c.streams))
+	for rs := range c.streams {
+		streams = append(streams, rs)
+	}
+	c.mu.Unlock()
+
+	c.emit(StatusEvent{Type: StatusFailback, Endpoint: c.endpoints[0].addr})
+	for _, rs := range streams {
+		rs.mu.Lock()
+		if rs.endpoint != 0 && rs.attemptCancel != nil {
+			rs.attemptCancel(errFailback)
+		}
+		rs.mu.Unlock()
+	}
+}
+
+func (c *Client) track(rs *resumableStream) {
+	c.mu.Lock()
+	c.streams[rs] = struct{}{}
+	c.mu.Unlock()
+}
+
+func (c *Client) untrack(rs *resumableStream) {
+	c.mu.Lock()
+	delete(c.streams, rs)
+	c.mu.Unlock()
+}
@@ -0,0 +1,158 @@
+package thorclient
+
+import (
+	"context"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFailover(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection reset")
+
+	tests := []struct {
+		name       string
+		standbyErr error // fails every stream at once, nil to deliver a transaction
+		want       []byte
+		wantCode   codes.Code
+		// streams opened on the primary and standby
+		wantOpened [2]int32
+		wantStatus []StatusEventType
+	}{
+		{
+			name:       "moves to the standby",
+			want:       []byte{1, 2},
+			wantOpened: [2]int32{1, 1},
+			wantStatus: []StatusEventType{StatusFailover, StatusReconnecting, StatusReconnected},
+		},
+		{
+			// Without a message in between, a stream fails over once per
+			// endpoint and then retries the standby up to MaxRetries
+			name:       "fails over once until a message",
+			standbyErr: unavailable,
+			wantCode:   codes.Unavailable,
+			wantOpened: [2]int32{1, 2},
+			wantStatus: []StatusEventType{StatusFailover, StatusReconnecting, StatusReconnected, StatusReconnecting, StatusReconnected, StatusReconnectFailed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opened [2]atomic.Int32
+			primary, _ := startPublisher(t, "", func(ctx context.Context, _ any, send func(*pb.MessageWrapper) error) error {
+				opened[0].Add(1)
+				if tt.standbyErr == nil {
+					if err := send(txMessage(1, []byte{1})); err != nil {
+						return err
+					}
+				}
+				return unavailable
+			})
+			standby, _ := startPublisher(t, "", func(ctx context.Context, _ any, send func(*pb.MessageWrapper) error) error {
+				opened[1].Add(1)
+				if tt.standbyErr != nil {
+					return tt.standbyErr
+				}
+				if err := send(txMessage(1, []byte{2})); err != nil {
+					return err
+				}
+				return idle(ctx)
+			})
+			c, err := NewClient(Config{ServerAddrs: []string{primary, standby}, FailbackAfter: -1, MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			s, err := c.SubscribeToTransactions(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			var got []byte
+			for range tt.want {
+				tx, err := s.Recv()
+				if err != nil {
+					t.Fatalf("Recv after %v: %v", got, err)
+				}
+				got = append(got, tx.Signature...)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("received %v, want %v", got, tt.want)
+			}
+			if tt.wantCode != codes.OK {
+				if _, err := s.Recv(); status.Code(err) != tt.wantCode {
+					t.Errorf("final Recv error = %v, want code %v", err, tt.wantCode)
+				}
+			}
+			if got := [2]int32{opened[0].Load(), opened[1].Load()}; got != tt.wantOpened {
+				t.Errorf("streams opened on primary and standby %v, want %v", got, tt.wantOpened)
+			}
+			if got := statusTypes(c); !slices.Equal(got, tt.wantStatus) {
+				t.Errorf("status events %v, want %v", got, tt.wantStatus)
+			}
+			if c.Endpoint() != standby {
+				t.Errorf("Endpoint() = %s, want the standby %s", c.Endpoint(), standby)
+			}
+		})
+	}
+}
+
+func TestFailback(t *testing.T) {
+	sendOne := func(sig byte) serveFunc {
+		return func(ctx context.Context, _ any, send func(*pb.MessageWrapper) error) error {
+			if err := send(txMessage(1, []byte{sig})); err != nil {
+				return err
+			}
+			return idle(ctx)
+		}
+	}
+	primary, primaryServer := startPublisher(t, "", sendOne(1))
+	standby, _ := startPublisher(t, "", sendOne(2))
+
+	// Redial the restarted primary quickly
+	redial := grpc.WithConnectParams(grpc.ConnectParams{
+		Backoff:           backoff.Config{BaseDelay: 10 * time.Millisecond, Multiplier: 1, MaxDelay: 10 * time.Millisecond},
+		MinConnectTimeout: time.Second,
+	})
+	c, err := NewClient(Config{ServerAddrs: []string{primary, standby}, FailbackAfter: 50 * time.Millisecond, InitialBackoff: time.Millisecond, DialOptions: []grpc.DialOption{redial}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s, err := c.SubscribeToTransactions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	recv := func(want byte) {
+		t.Helper()
+		if tx, err := s.Recv(); err != nil || tx.Signature[0] != want {
+			t.Fatalf("Recv() = %v, %v, want transaction %d", tx, err, want)
+		}
+	}
+	recv(1)
+	primaryServer.Stop()
+	recv(2)
+	if c.Endpoint() != standby {
+		t.Fatalf("Endpoint() = %s after the primary stopped, want %s", c.Endpoint(), standby)
+	}
+
+	// The stream returns to the primary once it is back
+	startPublisher(t, primary, sendOne(3))
+	recv(3)
+	if c.Endpoint() != primary {
+		t.Errorf("Endpoint() = %s after failback, want %s", c.Endpoint(), primary)
+	}
+	want := []StatusEventType{StatusFailover, StatusReconnecting, StatusReconnected, StatusFailback}
+	if got := statusTypes(c); !slices.Equal(got, want) {
+		t.Errorf("status events %v, want %v", got, want)
+	}
+}
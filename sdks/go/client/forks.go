@@ -0,0 +1,140 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// Rollback reports processed slots that were abandoned for a competing fork,
+// with the signatures of the transactions observed in them
+type Rollback struct {
+	Slots      []uint64
+	Signatures [][]byte
+}
+
+// ForkDetector turns the orphaned branches found by a SlotTracker into
+// Rollback events for the transactions passed to Observe
+type ForkDetector struct {
+	tracker *SlotTracker
+
+	mu      sync.Mutex
+	sigs    map[uint64][][]byte
+	queue   []Rollback
+	pending chan struct{}
+
+	out chan Rollback
+}
+
+// DetectForks starts a ForkDetector on tracker. The Rollbacks channel is
+// closed when ctx is done or the tracker stops.
+func DetectForks(ctx context.Context, tracker *SlotTracker) *ForkDetector {
+	d := &ForkDetector{
+		tracker: tracker,
+		sigs:    make(map[uint64][][]byte),
+		pending: make(chan struct{}, 1),
+		out:     make(chan Rollback),
+	}
+	tracker.onRoot(d.rooted)
+	go d.run(ctx)
+	return d
+}
+
+// Rollbacks returns the channel on which rollbacks are delivered. It must be
+// drained; rollbacks queue up until it is.
+func (d *ForkDetector) Rollbacks() <-chan Rollback {
+	return d.out
+}
+
+// Observe records a processed transaction so that it is included in the
+// rollback of its slot. Transactions of slots already rooted are ignored,
+// and those of slots already abandoned are rolled back at once.
+func (d *ForkDetector) Observe(tx *pb.TransactionEvent) {
+	d.mu.Lock()
+	d.sigs[tx.Slot] = append(d.sigs[tx.Slot], tx.Signature)
+	d.mu.Unlock()
+
+	// The tracker may have decided on the slot before it was recorded
+	err, decided := d.tracker.outcome(tx.Slot, CommitmentRooted)
+	if !decided {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	sigs := d.sigs[tx.Slot]
+	delete(d.sigs, tx.Slot)
+	if errors.Is(err, ErrSlotSkipped) && len(sigs) > 0 {
+		d.enqueue(Rollback{Slots: []uint64{tx.Slot}, Signatures: sigs})
+	}
+}
+
+// rooted is called by the tracker when its root advances
+func (d *ForkDetector) rooted(root uint64, orphaned []uint64) {
+	if len(orphaned) > 0 {
+		d.rollback(orphaned)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for slot := range d.sigs {
+		if slot <= root {
+			delete(d.sigs, slot)
+		}
+	}
+}
+
+// rollback queues a Rollback for slots with the signatures still recorded
+func (d *ForkDetector) rollback(slots []uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	rb := Rollback{Slots: slices.Clone(slots)}
+	for _, slot := range slots {
+		rb.Signatures = append(rb.Signatures, d.sigs[slot]...)
+		delete(d.sigs, slot)
+	}
+	d.enqueue(rb)
+}
+
+// enqueue hands rb to the delivery goroutine; d.mu must be held
+func (d *ForkDetector) enqueue(rb Rollback) {
+	d.queue = append(d.queue, rb)
+	select {
+	case d.pending <- struct{}{}:
+	default:
+	}
+}
+
+func (d *ForkDetector) run(ctx context.Context) {
+	defer close(d.out)
+	for stopped := false; ; {
+		d.mu.Lock()
+		queue := d.queue
+		d.queue = nil
+		d.mu.Unlock()
+
+		for _, rb := range queue {
+			select {
+			case d.out <- rb:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		if stopped {
+			return
+		}
+		select {
+		case <-d.pending:
+		case <-d.tracker.Done():
+			// Deliver what the tracker found before it stopped
+			stopped = true
+		case <-ctx.Done():
+			return
+		}
+	}
+}
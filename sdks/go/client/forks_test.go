@@ -0,0 +1,103 @@
+package thorclient
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+func TestForkDetector(t *testing.T) {
+	// A step is a slot status event, or a transaction when sig is set
+	type step struct {
+		slotUpdate
+		sig string
+	}
+	tx := func(slot uint64, sig string) step {
+		return step{slotUpdate: slotUpdate{slot: slot}, sig: sig}
+	}
+	ev := func(slot, parent uint64, status int32) step {
+		return step{slotUpdate: slotUpdate{slot, parent, status}}
+	}
+	type rollback struct {
+		slots []uint64
+		sigs  []string
+	}
+
+	tests := []struct {
+		name      string
+		steps     []step
+		rollbacks []rollback
+	}{
+		{
+			name: "abandoned branch",
+			steps: []step{
+				ev(100, 99, 0), ev(101, 100, 0), ev(102, 100, 0),
+				tx(101, "a"), tx(102, "b"), tx(101, "c"),
+				ev(100, 0, 2), ev(102, 0, 2),
+			},
+			rollbacks: []rollback{{[]uint64{101}, []string{"a", "c"}}},
+		},
+		{
+			name: "descendants of abandoned branch",
+			steps: []step{
+				ev(100, 99, 0), ev(101, 100, 0), ev(102, 100, 0), ev(104, 101, 0),
+				tx(104, "d"), tx(101, "a"),
+				ev(102, 0, 2),
+			},
+			rollbacks: []rollback{{[]uint64{101, 104}, []string{"a", "d"}}},
+		},
+		{
+			name: "transaction of a slot already abandoned",
+			steps: []step{
+				ev(100, 99, 0), ev(102, 100, 2), ev(101, 100, 0),
+				tx(101, "a"),
+			},
+			rollbacks: []rollback{{[]uint64{101}, []string{"a"}}},
+		},
+		{
+			name: "dead slot",
+			steps: []step{
+				ev(100, 99, 0), tx(101, "a"), ev(101, 0, slotStatusDead),
+			},
+			rollbacks: []rollback{{[]uint64{101}, []string{"a"}}},
+		},
+		{
+			name: "rooted transactions stay",
+			steps: []step{
+				ev(100, 99, 0), tx(100, "a"), ev(101, 100, 0), tx(101, "b"),
+				ev(101, 0, 2), tx(100, "c"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, stop := newTestTracker(t)
+			d := DetectForks(context.Background(), tracker)
+			for _, s := range tt.steps {
+				if s.sig != "" {
+					d.Observe(&pb.TransactionEvent{Slot: s.slot, Signature: []byte(s.sig)})
+				} else {
+					tracker.observe(s.proto())
+				}
+			}
+			// The detector delivers what it queued, then closes the channel
+			stop()
+
+			var got []rollback
+			for rb := range d.Rollbacks() {
+				r := rollback{slots: rb.Slots}
+				for _, sig := range rb.Signatures {
+					r.sigs = append(r.sigs, string(sig))
+				}
+				got = append(got, r)
+			}
+			if !slices.EqualFunc(got, tt.rollbacks, func(a, b rollback) bool {
+				return slices.Equal(a.slots, b.slots) && slices.Equal(a.sigs, b.sigs)
+			}) {
+				t.Errorf("rollbacks = %v, want %v", got, tt.rollbacks)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package thorclient
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mr-tron/base58"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// ErrAccountIndex means an instruction refers to an account index beyond
+// the message's keys and loaded addresses
+var ErrAccountIndex = errors.New("account index out of range")
+
+// ResolvedInstruction is an instruction with its program and accounts
+// resolved to base58 pubkeys, and the instructions it invoked
+type ResolvedInstruction struct {
+	ProgramID string
+	Accounts  []ResolvedAccount
+	Data      []byte
+	// StackHeight is 1 for top-level instructions and grows by one per CPI
+	StackHeight uint32
+	// Inner are the instructions this one invoked, in execution order
+	Inner []*ResolvedInstruction
+}
+
+// ResolvedAccount is an instruction account with its message permissions
+type ResolvedAccount struct {
+	Pubkey     string
+	IsSigner   bool
+	IsWritable bool
+}
+
+// ResolveInstructions resolves a transaction's top-level instructions and
+// nests its inner instructions under them by stack height. Inner
+// instructions without a recorded stack height are placed directly under
+// their top-level instruction. It returns nil if the event has no message.
+func ResolveInstructions(tx *pb.TransactionEvent) ([]*ResolvedInstruction, error) {
+	msg := tx.GetTransaction().GetMessage()
+	if msg == nil {
+		return nil, nil
+	}
+	keys := resolveKeys(msg)
+
+	top := make([]*ResolvedInstruction, len(msg.Instructions))
+	for i, ix := range msg.Instructions {
+		resolved, err := resolveInstruction(ix, keys, 1)
+		if err != nil {
+			return nil, fmt.Errorf("instruction %d: %w", i, err)
+		}
+		top[i] = resolved
+	}
+
+	for _, inner := range tx.GetTransactionStatusMeta().GetInnerInstructions() {
+		if int(inner.Index) >= len(top) {
+			return nil, fmt.Errorf("inner instructions of missing instruction %d", inner.Index)
+		}
+		// path holds the most recent instruction at each stack height
+		path := []*ResolvedInstruction{top[inner.Index]}
+		for j, ix := range inner.Instructions {
+			height := uint32(2)
+			if ix.StackHeight != nil {
+				height = max(*ix.StackHeight, 2)
+			}
+			resolved, err := resolveInstruction(ix.Instruction, keys, height)
+			if err != nil {
+				return nil, fmt.Errorf("inner instruction %d.%d: %w", inner.Index, j, err)
+			}
+
+			path = path[:min(len(path), int(height)-1)]
+			parent := path[len(path)-1]
+			parent.Inner = append(parent.Inner, resolved)
+			path = append(path, resolved)
+		}
+	}
+	return top, nil
+}
+
+// resolveKeys returns the static keys followed by the loaded writable and
+// readonly addresses, with their permissions
+func resolveKeys(msg *pb.Message) []ResolvedAccount {
+	loaded := msg.GetLoadedAddresses()
+	static := len(msg.AccountKeys)
+	keys := make([]ResolvedAccount, 0, static+len(loaded.GetWritable())+len(loaded.GetReadonly()))
+
+	header := msg.GetHeader()
+	signers := int(header.GetNumRequiredSignatures())
+	for i, key := range msg.AccountKeys {
+		var writable bool
+		if i < signers {
+			writable = i < signers-int(header.GetNumReadonlySignedAccounts())
+		} else {
+			writable = i < static-int(header.GetNumReadonlyUnsignedAccounts())
+		}
+		keys = append(keys, ResolvedAccount{Pubkey: base58.Encode(key), IsSigner: i < signers, IsWritable: writable})
+	}
+	for _, key := range loaded.GetWritable() {
+		keys = append(keys, ResolvedAccount{Pubkey: base58.Encode(key), IsWritable: true})
+	}
+	for _, key := range loaded.GetReadonly() {
+		keys = append(keys, ResolvedAccount{Pubkey: base58.Encode(key)})
+	}
+
+	// Prefer the permissions the validator computed, which demote reserved
+	// accounts and invoked programs
+	if len(msg.IsWritable) == len(keys) {
+		for i := range keys {
+			keys[i].IsWritable = msg.IsWritable[i]
+		}
+	}
+	return keys
+}
+
+func resolveInstruction(ix *pb.CompiledInstruction, keys []ResolvedAccount, height uint32) (*ResolvedInstruction, error) {
+	if ix == nil {
+		return nil, errors.New("missing instruction")
+	}
+	if int(ix.ProgramIdIndex) >= len(keys) {
+		return nil, fmt.Errorf("%w: program %d of %d keys", ErrAccountIndex, ix.ProgramIdIndex, len(keys))
+	}
+
+	accounts := make([]ResolvedAccount, len(ix.Accounts))
+	for i, index := range ix.Accounts {
+		if int(index) >= len(keys) {
+			return nil, fmt.Errorf("%w: account %d of %d keys", ErrAccountIndex, index, len(keys))
+		}
+		accounts[i] = keys[index]
+	}
+	return &ResolvedInstruction{
+		ProgramID:   keys[ix.ProgramIdIndex].Pubkey,
+		Accounts:    accounts,
+		Data:        ix.Data,
+		StackHeight: height,
+	}, nil
+}
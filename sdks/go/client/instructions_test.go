@@ -0,0 +1,140 @@
+package thorclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mr-tron/base58"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+func TestResolveInstructions(t *testing.T) {
+	// Keys are named k<n> in the expected trees by their first byte
+	key := func(n byte) []byte {
+		k := make([]byte, 32)
+		k[0] = n
+		return k
+	}
+	names := make(map[string]string)
+	for n := byte(1); n <= 8; n++ {
+		names[base58.Encode(key(n))] = fmt.Sprintf("k%d", n)
+	}
+	// tree renders instructions as program(accounts){inner ...}
+	var tree func(ixs []*ResolvedInstruction) string
+	tree = func(ixs []*ResolvedInstruction) string {
+		var parts []string
+		for _, ix := range ixs {
+			var accounts []string
+			for _, a := range ix.Accounts {
+				perm := ""
+				if a.IsSigner {
+					perm += "s"
+				}
+				if a.IsWritable {
+					perm += "w"
+				}
+				accounts = append(accounts, names[a.Pubkey]+perm)
+			}
+			s := fmt.Sprintf("%s@%d(%s)", names[ix.ProgramID], ix.StackHeight, strings.Join(accounts, ","))
+			if len(ix.Inner) > 0 {
+				s += "{" + tree(ix.Inner) + "}"
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, " ")
+	}
+	height := func(h uint32) *uint32 { return &h }
+	inner := func(program uint32, h *uint32) *pb.InnerInstruction {
+		return &pb.InnerInstruction{Instruction: &pb.CompiledInstruction{ProgramIdIndex: program}, StackHeight: h}
+	}
+	// message has two signers, one of them readonly, and one readonly
+	// unsigned key among four static keys, plus one writable and one
+	// readonly loaded address
+	message := func(ixs ...*pb.CompiledInstruction) *pb.Message {
+		return &pb.Message{
+			Header:          &pb.MessageHeader{NumRequiredSignatures: 2, NumReadonlySignedAccounts: 1, NumReadonlyUnsignedAccounts: 1},
+			AccountKeys:     [][]byte{key(1), key(2), key(3), key(4)},
+			LoadedAddresses: &pb.LoadedAddresses{Writable: [][]byte{key(5)}, Readonly: [][]byte{key(6)}},
+			Instructions:    ixs,
+		}
+	}
+
+	tests := []struct {
+		name  string
+		msg   *pb.Message
+		inner []*pb.InnerInstructions
+		want  string
+		err   error
+	}{
+		{
+			name: "permissions from header and loaded addresses",
+			msg:  message(&pb.CompiledInstruction{ProgramIdIndex: 3, Accounts: []uint32{0, 1, 2, 4, 5}}),
+			want: "k4@1(k1sw,k2s,k3w,k5w,k6)",
+		},
+		{
+			name: "validator permissions override the header",
+			msg: func() *pb.Message {
+				msg := message(&pb.CompiledInstruction{ProgramIdIndex: 3, Accounts: []uint32{0, 2, 4}})
+				msg.IsWritable = []bool{true, false, false, false, true, false}
+				return msg
+			}(),
+			want: "k4@1(k1sw,k3,k5w)",
+		},
+		{
+			name: "nested by stack height",
+			msg:  message(&pb.CompiledInstruction{ProgramIdIndex: 3}, &pb.CompiledInstruction{ProgramIdIndex: 5}),
+			inner: []*pb.InnerInstructions{{Index: 0, Instructions: []*pb.InnerInstruction{
+				inner(5, height(2)),
+				inner(3, height(3)),
+				inner(2, height(4)),
+				inner(1, height(3)),
+				inner(0, height(2)),
+			}}},
+			want: "k4@1(){k6@2(){k4@3(){k3@4()} k2@3()} k1@2()} k6@1()",
+		},
+		{
+			name: "missing stack heights are direct children",
+			msg:  message(&pb.CompiledInstruction{ProgramIdIndex: 3}, &pb.CompiledInstruction{ProgramIdIndex: 5}),
+			inner: []*pb.InnerInstructions{{Index: 1, Instructions: []*pb.InnerInstruction{
+				inner(4, nil),
+				inner(4, nil),
+			}}},
+			want: "k4@1() k6@1(){k5@2() k5@2()}",
+		},
+		{
+			name: "account beyond the loaded addresses",
+			msg:  message(&pb.CompiledInstruction{ProgramIdIndex: 3, Accounts: []uint32{6}}),
+			err:  ErrAccountIndex,
+		},
+		{
+			name: "inner program beyond the keys",
+			msg:  message(&pb.CompiledInstruction{ProgramIdIndex: 3}),
+			inner: []*pb.InnerInstructions{{Index: 0, Instructions: []*pb.InnerInstruction{
+				inner(9, height(2)),
+			}}},
+			err: ErrAccountIndex,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := &pb.TransactionEvent{
+				Transaction:           &pb.SanitizedTransaction{Message: tt.msg},
+				TransactionStatusMeta: &pb.TransactionStatusMeta{InnerInstructions: tt.inner},
+			}
+			ixs, err := ResolveInstructions(tx)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ResolveInstructions error = %v, want %v", err, tt.err)
+			}
+			if got := tree(ixs); err == nil && got != tt.want {
+				t.Errorf("ResolveInstructions = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if ixs, err := ResolveInstructions(&pb.TransactionEvent{}); ixs != nil || err != nil {
+		t.Errorf("ResolveInstructions without a message = %v, %v, want nil, nil", ixs, err)
+	}
+}
@@ -0,0 +1,278 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"sync"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// MultiClient subscribes to the same streams on several endpoints at once and
+// delivers each event once, from whichever endpoint receives it first
+type MultiClient struct {
+	clients []*Client
+
+	mu        sync.Mutex
+	endpoints []endpointStats
+	unique    uint64
+}
+
+// endpointStats accumulates the race results of one endpoint
+type endpointStats struct {
+	addr     string
+	received uint64
+	wins     uint64
+	lag      time.Duration
+}
+
+// EndpointStats reports how one endpoint of a MultiClient performs
+type EndpointStats struct {
+	Endpoint string
+	// Received counts the events the endpoint delivered, including duplicates
+	Received uint64
+	// Wins counts the events the endpoint delivered first
+	Wins uint64
+	// WinRate is Wins over the number of distinct events seen on any endpoint
+	WinRate float64
+	// MeanLag is the average delay behind the first arrival of the events the
+	// endpoint delivered, counting wins as zero
+	MeanLag time.Duration
+}
+
+// NewMultiClient connects to every endpoint described by configs
+func NewMultiClient(configs ...Config) (*MultiClient, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("%w: no endpoints configured", ErrInvalidConfig)
+	}
+
+	m := &MultiClient{}
+	for _, cfg := range configs {
+		client, err := NewClient(cfg)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("endpoint %s: %w", cfg.ServerAddr, err)
+		}
+		m.clients = append(m.clients, client)
+		m.endpoints = append(m.endpoints, endpointStats{addr: client.Endpoint()})
+	}
+	return m, nil
+}
+
+// Clients returns the underlying client of each endpoint, e.g. to watch their
+// Status channels
+func (m *MultiClient) Clients() []*Client {
+	return m.clients
+}
+
+// Close closes the connections to all endpoints
+func (m *MultiClient) Close() error {
+	var errs []error
+	for _, client := range m.clients {
+		errs = append(errs, client.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Stats returns the race results of each endpoint across all streams
+func (m *MultiClient) Stats() []EndpointStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make([]EndpointStats, len(m.endpoints))
+	for i, e := range m.endpoints {
+		stats[i] = EndpointStats{Endpoint: e.addr, Received: e.received, Wins: e.wins}
+		if m.unique > 0 {
+			stats[i].WinRate = float64(e.wins) / float64(m.unique)
+		}
+		if e.received > 0 {
+			stats[i].MeanLag = e.lag / time.Duration(e.received)
+		}
+	}
+	return stats
+}
+
+// record accounts an arrival on endpoint, lag behind the first arrival
+func (m *MultiClient) record(endpoint int, lag time.Duration, first bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := &m.endpoints[endpoint]
+	e.received++
+	e.lag += lag
+	if first {
+		e.wins++
+		m.unique++
+	}
+}
+
+// overtake accounts an arrival on endpoint that was stamped before the one
+// credited with the win but processed after it: the win moves to endpoint
+// and the former winner is charged its lag
+func (m *MultiClient) overtake(endpoint, winner int, lag time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := &m.endpoints[endpoint]
+	e.received++
+	e.wins++
+	w := &m.endpoints[winner]
+	w.wins--
+	w.lag += lag
+}
+
+// SubscribeToTransactions races transaction streams, keyed by signature
+func (m *MultiClient) SubscribeToTransactions(ctx context.Context) (*RaceStream[*pb.TransactionEvent], error) {
+	return race(ctx, m, SignatureKey, func(ctx context.Context, c *Client) (raceSource[*pb.TransactionEvent], error) {
+		return c.SubscribeToTransactions(ctx)
+	})
+}
+
+// SubscribeToSlotStatus races slot streams, keyed by slot and status
+func (m *MultiClient) SubscribeToSlotStatus(ctx context.Context) (*RaceStream[*pb.SlotStatusEvent], error) {
+	return race(ctx, m, SlotStatusKey, func(ctx context.Context, c *Client) (raceSource[*pb.SlotStatusEvent], error) {
+		return c.SubscribeToSlotStatus(ctx)
+	})
+}
+
+// SubscribeToWalletTransactions races wallet streams, keyed by signature
+func (m *MultiClient) SubscribeToWalletTransactions(ctx context.Context, wallets []string) (*RaceStream[*pb.TransactionEvent], error) {
+	return race(ctx, m, SignatureKey, func(ctx context.Context, c *Client) (raceSource[*pb.TransactionEvent], error) {
+		return c.SubscribeToWalletTransactions(ctx, wallets)
+	})
+}
+
+// SubscribeToAccountUpdates races account streams, keyed by pubkey and write_version
+func (m *MultiClient) SubscribeToAccountUpdates(ctx context.Context, accounts, owners []string) (*RaceStream[*pb.SubscribeUpdateAccountInfo], error) {
+	return race(ctx, m, AccountVersionKey, func(ctx context.Context, c *Client) (raceSource[*pb.SubscribeUpdateAccountInfo], error) {
+		return c.SubscribeToAccountUpdates(ctx, accounts, owners)
+	})
+}
+
+// raceSource is the stream of one endpoint in a race
+type raceSource[T any] interface {
+	Receiver[T]
+	Close()
+}
+
+// RaceStream merges the same subscription on several endpoints, returning
+// each event from the first endpoint to deliver it. It keeps running while
+// any endpoint does.
+type RaceStream[T any] struct {
+	multi   *MultiClient
+	key     KeyFunc[T]
+	seen    *recentSet
+	sources []raceSource[T]
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	out     chan raceResult[T]
+	failed  int
+	lastErr error
+}
+
+type raceResult[T any] struct {
+	endpoint int
+	event    T
+	at       time.Time
+	err      error
+}
+
+// race subscribes on every endpoint with open. It fails only if no endpoint
+// could be subscribed.
+func race[T any](ctx context.Context, m *MultiClient, key KeyFunc[T], open func(context.Context, *Client) (raceSource[T], error)) (*RaceStream[T], error) {
+	ctx, cancel := context.WithCancel(ctx)
+	s := &RaceStream[T]{
+		multi:   m,
+		key:     key,
+		seen:    newRecentSet(dedupWindow),
+		sources: make([]raceSource[T], len(m.clients)),
+		ctx:     ctx,
+		cancel:  cancel,
+		out:     make(chan raceResult[T], 256),
+	}
+
+	var errs []error
+	for i, client := range m.clients {
+		source, err := open(ctx, client)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("endpoint %s: %w", m.endpoints[i].addr, err))
+			s.failed++
+			continue
+		}
+		s.sources[i] = source
+		go s.pump(i, source)
+	}
+	if s.failed == len(m.clients) {
+		cancel()
+		return nil, errors.Join(errs...)
+	}
+	return s, nil
+}
+
+// Recv blocks until the next event not yet delivered by another endpoint.
+// It returns an error once every endpoint has failed.
+func (s *RaceStream[T]) Recv() (T, error) {
+	for {
+		select {
+		case r := <-s.out:
+			if r.err != nil {
+				s.failed++
+				s.lastErr = r.err
+				if s.failed < len(s.sources) {
+					continue
+				}
+				s.Close()
+				return r.event, fmt.Errorf("all endpoints failed, last: %w", s.lastErr)
+			}
+			// Events of different endpoints can be processed out of arrival
+			// order, so the win goes to the earliest arrival stamp
+			first, ok := s.seen.addAt(s.key(r.event), arrival{at: r.at, source: r.endpoint})
+			switch {
+			case ok:
+				s.multi.record(r.endpoint, 0, true)
+				return r.event, nil
+			case r.at.Before(first.at) && first.source != r.endpoint:
+				s.multi.overtake(r.endpoint, first.source, first.at.Sub(r.at))
+			default:
+				s.multi.record(r.endpoint, max(r.at.Sub(first.at), 0), false)
+			}
+		case <-s.ctx.Done():
+			var zero T
+			return zero, s.ctx.Err()
+		}
+	}
+}
+
+// All returns an iterator over the merged events, see Stream.All
+func (s *RaceStream[T]) All() iter.Seq2[T, error] {
+	return all(s)
+}
+
+// Close ends the subscription on all endpoints
+func (s *RaceStream[T]) Close() {
+	s.cancel()
+	for _, source := range s.sources {
+		if source != nil {
+			source.Close()
+		}
+	}
+}
+
+// pump forwards the events of one endpoint, stamped with their arrival time
+func (s *RaceStream[T]) pump(endpoint int, source raceSource[T]) {
+	for {
+		event, err := source.Recv()
+		select {
+		case s.out <- raceResult[T]{endpoint: endpoint, event: event, at: time.Now(), err: err}:
+		case <-s.ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRecentSet(t *testing.T) {
+	r := newRecentSet(2)
+	for _, key := range []string{"a", "b"} {
+		if !r.add(key) {
+			t.Errorf("add(%q) reported a duplicate", key)
+		}
+	}
+	if r.add("a") {
+		t.Error("add(a) twice reported it new")
+	}
+	// A third key evicts the oldest
+	r.add("c")
+	if r.contains("a") || !r.contains("b") || !r.contains("c") {
+		t.Errorf("after evicting a: contains a=%v b=%v c=%v", r.contains("a"), r.contains("b"), r.contains("c"))
+	}
+
+	t0 := time.Now()
+	r = newRecentSet(4)
+	r.addAt("k", arrival{at: t0.Add(time.Millisecond), source: 0})
+	if first, added := r.addAt("k", arrival{at: t0, source: 1}); added || first.source != 0 {
+		t.Errorf("addAt earlier = %+v, %v, want the recorded arrival of source 0", first, added)
+	}
+	// The earlier arrival replaced the recorded one
+	if first, _ := r.addAt("k", arrival{at: t0.Add(time.Second), source: 2}); first.source != 1 || !first.at.Equal(t0) {
+		t.Errorf("recorded arrival %+v, want source 1 at t0", first)
+	}
+}
+
+func TestRaceCredit(t *testing.T) {
+	t0 := time.Now()
+	tx := func(sig byte) *pb.TransactionEvent {
+		return &pb.TransactionEvent{Signature: []byte{sig}}
+	}
+	// Results in processing order; arrival stamps may disagree with it
+	results := []raceResult[*pb.TransactionEvent]{
+		{endpoint: 0, event: tx(1), at: t0.Add(time.Millisecond)},
+		{endpoint: 1, event: tx(1), at: t0}, // arrived first, processed second
+		{endpoint: 0, event: tx(2), at: t0},
+		{endpoint: 1, event: tx(2), at: t0.Add(2 * time.Millisecond)},
+		{endpoint: 1, event: tx(3), at: t0},
+		{endpoint: 0, err: errors.New("endpoint 0 failed")},
+		{endpoint: 1, event: tx(3), at: t0.Add(time.Second)}, // duplicate from the same endpoint
+		{endpoint: 1, err: errors.New("endpoint 1 failed")},
+	}
+
+	m := &MultiClient{endpoints: []endpointStats{{addr: "a"}, {addr: "b"}}}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &RaceStream[*pb.TransactionEvent]{
+		multi:   m,
+		key:     SignatureKey,
+		seen:    newRecentSet(16),
+		sources: make([]raceSource[*pb.TransactionEvent], 2),
+		ctx:     ctx,
+		cancel:  cancel,
+		out:     make(chan raceResult[*pb.TransactionEvent], len(results)),
+	}
+	for _, r := range results {
+		s.out <- r
+	}
+
+	var got []byte
+	var err error
+	for {
+		var tx *pb.TransactionEvent
+		if tx, err = s.Recv(); err != nil {
+			break
+		}
+		got = append(got, tx.Signature...)
+	}
+	if !slices.Equal(got, []byte{1, 2, 3}) {
+		t.Errorf("delivered %v, want each transaction once", got)
+	}
+	if err == nil || err.Error() != "all endpoints failed, last: endpoint 1 failed" {
+		t.Errorf("final error = %v, want the last endpoint's failure", err)
+	}
+
+	want := []EndpointStats{
+		// Won tx 2 and was charged 1ms for losing tx 1 to an earlier arrival
+		{Endpoint: "a", Received: 2, Wins: 1, WinRate: 1.0 / 3, MeanLag: time.Millisecond / 2},
+		// Won tx 1 and 3, 2ms behind on tx 2 and 1s on its own duplicate of tx 3
+		{Endpoint: "b", Received: 4, Wins: 2, WinRate: 2.0 / 3, MeanLag: (2*time.Millisecond + time.Second) / 4},
+	}
+	if stats := m.Stats(); !slices.Equal(stats, want) {
+		t.Errorf("Stats() = %+v, want %+v", stats, want)
+	}
+}
+
+func TestMultiClient(t *testing.T) {
+	if _, err := NewMultiClient(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("NewMultiClient() = %v, want ErrInvalidConfig", err)
+	}
+
+	serve := func(fail bool) serveFunc {
+		return func(ctx context.Context, _ any, send func(*pb.MessageWrapper) error) error {
+			for sig := byte(1); sig <= 3; sig++ {
+				if err := send(txMessage(1, []byte{sig})); err != nil {
+					return err
+				}
+			}
+			if fail {
+				return status.Error(codes.PermissionDenied, "denied")
+			}
+			return idle(ctx)
+		}
+	}
+	healthy, _ := startPublisher(t, "", serve(false))
+	failing, _ := startPublisher(t, "", serve(true))
+	m, err := NewMultiClient(Config{ServerAddr: healthy}, Config{ServerAddr: failing, MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if len(m.Clients()) != 2 {
+		t.Fatalf("%d clients, want 2", len(m.Clients()))
+	}
+
+	s, err := m.SubscribeToTransactions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	var got []byte
+	for range 3 {
+		tx, err := s.Recv()
+		if err != nil {
+			t.Fatalf("Recv after %v: %v", got, err)
+		}
+		got = append(got, tx.Signature...)
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, []byte{1, 2, 3}) {
+		t.Errorf("received %v, want each transaction once", got)
+	}
+
+	// The stream outlives the failed endpoint, processing the duplicates
+	// while waiting for more
+	extra := make(chan error, 1)
+	go func() {
+		_, err := s.Recv()
+		extra <- err
+	}()
+	eventually(t, "both endpoints' transactions", func() bool {
+		stats := m.Stats()
+		return stats[0].Received == 3 && stats[1].Received == 3
+	})
+	var wins uint64
+	for i, stats := range m.Stats() {
+		if want := []string{healthy, failing}[i]; stats.Endpoint != want {
+			t.Errorf("endpoint %d is %s, want %s", i, stats.Endpoint, want)
+		}
+		wins += stats.Wins
+	}
+	if wins != 3 {
+		t.Errorf("%d wins in total, want one per transaction", wins)
+	}
+	select {
+	case err := <-extra:
+		t.Errorf("Recv after one endpoint failed = %v, want to keep waiting", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
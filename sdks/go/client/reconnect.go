@@ -0,0 +1,324 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// statusBufferSize is the capacity of the channel returned by Client.Status
+const statusBufferSize = 64
+
+// SubscriptionType identifies the kind of server stream a subscription uses
+type SubscriptionType int
+
+const (
+	SubscriptionTransactions SubscriptionType = iota
+	SubscriptionSlots
+	SubscriptionWallets
+	SubscriptionAccounts
+	SubscriptionThor
+)
+
+func (t SubscriptionType) String() string {
+	switch t {
+	case SubscriptionTransactions:
+		return "transactions"
+	case SubscriptionSlots:
+		return "slots"
+	case SubscriptionWallets:
+		return "wallets"
+	case SubscriptionAccounts:
+		return "accounts"
+	case SubscriptionThor:
+		return "thor"
+	default:
+		return fmt.Sprintf("SubscriptionType(%d)", int(t))
+	}
+}
+
+// StatusEventType describes what happened to a stream
+type StatusEventType int
+
+const (
+	// StatusReconnecting is sent before waiting to re-establish a failed stream
+	StatusReconnecting StatusEventType = iota
+	// StatusReconnected is sent once the subscribe request has been replayed
+	StatusReconnected
+	// StatusReconnectFailed is sent when a stream gives up after MaxRetries attempts
+	StatusReconnectFailed
+	// StatusStalled is sent when a stream receives nothing for longer than its
+	// stall timeout
+	StatusStalled
+	// StatusFailover is sent when streams move to the next of Config.ServerAddrs
+	StatusFailover
+	// StatusFailback is sent when streams return to the first of Config.ServerAddrs
+	StatusFailback
+	// StatusGap is sent when a stream may have missed the slots in Gap
+	StatusGap
+	// StatusCheckpointFailed is sent when the CheckpointStore fails to load or save
+	StatusCheckpointFailed
+)
+
+func (t StatusEventType) String() string {
+	switch t {
+	case StatusReconnecting:
+		return "reconnecting"
+	case StatusReconnected:
+		return "reconnected"
+	case StatusReconnectFailed:
+		return "reconnect failed"
+	case StatusStalled:
+		return "stalled"
+	case StatusFailover:
+		return "failover"
+	case StatusFailback:
+		return "failback"
+	case StatusGap:
+		return "gap"
+	case StatusCheckpointFailed:
+		return "checkpoint failed"
+	default:
+		return fmt.Sprintf("StatusEventType(%d)", int(t))
+	}
+}
+
+// StatusEvent reports a change in the state of one of the client's streams
+type StatusEvent struct {
+	Type   StatusEventType
+	Stream SubscriptionType
+	// Attempt is the number of consecutive reconnect attempts so far
+	Attempt int
+	// Delay is the backoff waited before the attempt (StatusReconnecting only)
+	Delay time.Duration
+	// Idle is how long the stream went without a message (StatusStalled only)
+	Idle time.Duration
+	// Endpoint is the server streams moved to (StatusFailover and StatusFailback only)
+	Endpoint string
+	// Gap is the range of slots possibly missed (StatusGap only)
+	Gap *Gap
+	// Err is the error that caused the reconnect, stall or failover
+	Err  error
+	Time time.Time
+}
+
+// Status returns a channel reporting reconnect, stall and failover activity
+// of the client's streams. Events are dropped if the channel is not drained.
+func (c *Client) Status() <-chan StatusEvent {
+	return c.status
+}
+
+func (c *Client) emit(ev StatusEvent) {
+	ev.Time = time.Now()
+	select {
+	case c.status <- ev:
+	default:
+	}
+}
+
+// backoff returns the jittered delay before the given reconnect attempt
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.maxBackoff
+	// Double towards the cap without shifting past it, which would overflow
+	exp := c.initialBackoff
+	for i := 1; i < attempt && exp > 0 && exp < d; i++ {
+		if exp > d/2 {
+			exp = d
+		} else {
+			exp <<= 1
+		}
+	}
+	if exp > 0 && exp < d {
+		d = exp
+	}
+	// Spread attempts over [d/2, d] so clients don't reconnect in lockstep
+	return d/2 + rand.N(d/2+1)
+}
+
+// recvFunc receives the next message of an open server stream
+type recvFunc func() (*pb.MessageWrapper, error)
+
+// subscribeFunc opens a server stream on ep bound to ctx
+type subscribeFunc func(ctx context.Context, ep *endpoint) (recvFunc, error)
+
+// resumableStream re-establishes its server stream after transient failures by
+// replaying the original subscribe request
+type resumableStream struct {
+	client    *Client
+	kind      SubscriptionType
+	ctx       context.Context
+	cancel    context.CancelFunc
+	subscribe subscribeFunc
+	release   func()
+
+	recv      recvFunc
+	retries   int
+	failovers int
+
+	mu            sync.Mutex // guards the attempt for the watchdog and failback
+	endpoint      int
+	attempt       context.Context
+	attemptCancel context.CancelCauseFunc
+
+	lastMessage  atomic.Int64 // unix nanos, 0 before the first message
+	waitingSince atomic.Int64 // unix nanos, 0 while not blocked in Recv
+
+	checkpointKey string
+	highest       uint64 // highest slot received
+	observed      uint64 // highest slot known to be complete, if hasCheckpoint
+	hasCheckpoint bool
+	resumed       bool // a gap check is due with the next message
+}
+
+func (c *Client) openStream(ctx context.Context, info SubscriptionInfo, subscribe subscribeFunc) (*resumableStream, error) {
+	id, err := c.registry.acquire(info)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	rs := &resumableStream{
+		client:        c,
+		kind:          info.Type,
+		ctx:           ctx,
+		cancel:        cancel,
+		subscribe:     subscribe,
+		checkpointKey: checkpointKey(info),
+	}
+	rs.loadCheckpoint()
+	rs.release = sync.OnceFunc(func() {
+		c.registry.release(id)
+		c.untrack(rs)
+	})
+	c.track(rs)
+	// The quota is freed as soon as the stream's context ends
+	context.AfterFunc(ctx, rs.release)
+
+	if err := rs.connect(); err != nil {
+		rs.close()
+		return nil, fmt.Errorf("failed to subscribe: %w", Classify(err))
+	}
+	if timeout := c.stallTimeout(info.Type); timeout > 0 {
+		go rs.watch(timeout)
+	}
+	return rs, nil
+}
+
+// close cancels the server stream and frees its quota
+func (rs *resumableStream) close() {
+	rs.cancel()
+	rs.release()
+}
+
+// connect opens a new server stream on the active endpoint, releasing the
+// previous one
+func (rs *resumableStream) connect() error {
+	index, ep := rs.client.activeEndpoint()
+	rs.mu.Lock()
+	rs.endpoint = index
+	rs.mu.Unlock()
+
+	attemptCtx, attemptCancel := context.WithCancelCause(rs.ctx)
+	recv, err := rs.subscribe(attemptCtx, ep)
+	if err != nil {
+		attemptCancel(nil)
+		return err
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.attemptCancel != nil {
+		rs.attemptCancel(nil)
+	}
+	rs.recv, rs.attempt, rs.attemptCancel = recv, attemptCtx, attemptCancel
+	rs.resumed = true
+	return nil
+}
+
+// Recv receives the next message, reconnecting as needed
+func (rs *resumableStream) Recv() (*pb.MessageWrapper, error) {
+	for {
+		rs.waitingSince.Store(time.Now().UnixNano())
+		msg, err := rs.recv()
+		rs.waitingSince.Store(0)
+		if err == nil {
+			rs.lastMessage.Store(time.Now().UnixNano())
+			rs.retries, rs.failovers = 0, 0
+			rs.checkpoint(msg)
+			return msg, nil
+		}
+		if cause := context.Cause(rs.attempt); rs.ctx.Err() == nil {
+			switch {
+			case errors.Is(cause, ErrStreamStalled):
+				// Cancelled by the watchdog
+				err = cause
+			case errors.Is(cause, errFailback):
+				if err = rs.connect(); err == nil {
+					continue
+				}
+			}
+		}
+		if !rs.shouldReconnect(err) {
+			rs.close()
+			return nil, Classify(err)
+		}
+		if err := rs.reconnect(err); err != nil {
+			rs.close()
+			return nil, err
+		}
+	}
+}
+
+// reconnect retries connect with backoff. It moves to the next endpoint when
+// the current one is unhealthy or out of retries, at most once per endpoint
+// until a message is received.
+func (rs *resumableStream) reconnect(cause error) error {
+	for {
+		due := rs.retries >= rs.client.maxRetries || shouldFailover(cause)
+		if due && rs.failovers < len(rs.client.endpoints)-1 &&
+			rs.client.failover(rs.kind, rs.endpoint, cause) {
+			// Start over on the next endpoint
+			rs.retries = 0
+			rs.failovers++
+		}
+		if rs.retries >= rs.client.maxRetries {
+			rs.client.emit(StatusEvent{Type: StatusReconnectFailed, Stream: rs.kind, Attempt: rs.retries, Err: cause})
+			return fmt.Errorf("giving up after %d reconnect attempts: %w", rs.retries, Classify(cause))
+		}
+		rs.retries++
+
+		delay := rs.client.backoff(rs.retries)
+		rs.client.emit(StatusEvent{Type: StatusReconnecting, Stream: rs.kind, Attempt: rs.retries, Delay: delay, Err: cause})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-rs.ctx.Done():
+			timer.Stop()
+			return rs.ctx.Err()
+		case <-timer.C:
+		}
+
+		err := rs.connect()
+		if err == nil {
+			rs.client.emit(StatusEvent{Type: StatusReconnected, Stream: rs.kind, Attempt: rs.retries, Err: cause})
+			return nil
+		}
+		if !rs.shouldReconnect(err) {
+			return Classify(err)
+		}
+		cause = err
+	}
+}
+
+func (rs *resumableStream) shouldReconnect(err error) bool {
+	if rs.client.maxRetries < 0 || rs.ctx.Err() != nil {
+		return false
+	}
+	return IsRetryable(err)
+}
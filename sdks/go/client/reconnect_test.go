@@ -0,0 +1,258 @@
+package thorclient
+
+import (
+	"context"
+	"net"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// serveFunc produces the messages of one server stream opened with req. The
+// stream ends with its error.
+type serveFunc func(ctx context.Context, req any, send func(*pb.MessageWrapper) error) error
+
+// testPublisher is an EventPublisher and ThorStreamer whose streams are
+// produced by serve
+type testPublisher struct {
+	pb.UnimplementedEventPublisherServer
+	pb.UnimplementedThorStreamerServer
+	serve serveFunc
+}
+
+func (p *testPublisher) SubscribeToTransactions(req *emptypb.Empty, stream grpc.ServerStreamingServer[pb.StreamResponse]) error {
+	return p.serve(stream.Context(), req, wrapped(stream))
+}
+
+func (p *testPublisher) SubscribeToSlotStatus(req *emptypb.Empty, stream grpc.ServerStreamingServer[pb.StreamResponse]) error {
+	return p.serve(stream.Context(), req, wrapped(stream))
+}
+
+func (p *testPublisher) SubscribeToWalletTransactions(req *pb.SubscribeWalletRequest, stream grpc.ServerStreamingServer[pb.StreamResponse]) error {
+	return p.serve(stream.Context(), req, wrapped(stream))
+}
+
+func (p *testPublisher) SubscribeToAccountUpdates(req *pb.SubscribeAccountsRequest, stream grpc.ServerStreamingServer[pb.StreamResponse]) error {
+	return p.serve(stream.Context(), req, wrapped(stream))
+}
+
+func (p *testPublisher) StreamUpdates(req *pb.Empty, stream grpc.ServerStreamingServer[pb.MessageWrapper]) error {
+	return p.serve(stream.Context(), req, stream.Send)
+}
+
+// wrapped sends messages encoded in StreamResponses, as the EventPublisher does
+func wrapped(stream grpc.ServerStreamingServer[pb.StreamResponse]) func(*pb.MessageWrapper) error {
+	return func(msg *pb.MessageWrapper) error {
+		data, err := proto.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		return stream.Send(&pb.StreamResponse{Data: data})
+	}
+}
+
+// startPublisher serves a testPublisher on addr, or a free local port if addr
+// is empty, until the test ends
+func startPublisher(t *testing.T, addr string, serve serveFunc, opts ...grpc.ServerOption) (string, *grpc.Server) {
+	t.Helper()
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer(opts...)
+	publisher := &testPublisher{serve: serve}
+	pb.RegisterEventPublisherServer(server, publisher)
+	pb.RegisterThorStreamerServer(server, publisher)
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+	return lis.Addr().String(), server
+}
+
+// idle keeps a stream open without sending until it is cancelled
+func idle(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// txMessage wraps a transaction with the given slot and signature
+func txMessage(slot uint64, signature []byte) *pb.MessageWrapper {
+	return &pb.MessageWrapper{EventMessage: &pb.MessageWrapper_Transaction{
+		Transaction: &pb.TransactionEventWrapper{Transaction: &pb.TransactionEvent{Slot: slot, Signature: signature}},
+	}}
+}
+
+// eventually fails the test unless cond holds within a second
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// statusTypes drains the client's status channel
+func statusTypes(c *Client) []StatusEventType {
+	var types []StatusEventType
+	for {
+		select {
+		case ev := <-c.Status():
+			types = append(types, ev.Type)
+		default:
+			return types
+		}
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection reset")
+	// attempt is what one server stream sends before failing with err, or
+	// idling if err is nil
+	type attempt struct {
+		signatures []byte
+		err        error
+	}
+
+	tests := []struct {
+		name       string
+		maxRetries int
+		attempts   []attempt
+		want       []byte
+		wantCode   codes.Code // of the final Recv error, OK for none
+		wantStatus []StatusEventType
+	}{
+		{
+			name:       "resumes after transient error",
+			attempts:   []attempt{{[]byte{1}, unavailable}, {[]byte{2}, nil}},
+			want:       []byte{1, 2},
+			wantStatus: []StatusEventType{StatusReconnecting, StatusReconnected},
+		},
+		{
+			name:       "retries reset by a message",
+			maxRetries: 1,
+			attempts:   []attempt{{[]byte{1}, unavailable}, {[]byte{2}, unavailable}, {[]byte{3}, nil}},
+			want:       []byte{1, 2, 3},
+			wantStatus: []StatusEventType{StatusReconnecting, StatusReconnected, StatusReconnecting, StatusReconnected},
+		},
+		{
+			name:       "gives up after MaxRetries",
+			maxRetries: 2,
+			attempts:   []attempt{{nil, unavailable}, {nil, unavailable}, {nil, unavailable}},
+			wantCode:   codes.Unavailable,
+			wantStatus: []StatusEventType{StatusReconnecting, StatusReconnected, StatusReconnecting, StatusReconnected, StatusReconnectFailed},
+		},
+		{
+			name:     "fatal error ends the stream",
+			attempts: []attempt{{[]byte{1}, status.Error(codes.PermissionDenied, "denied")}},
+			want:     []byte{1},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:       "reconnection disabled",
+			maxRetries: -1,
+			attempts:   []attempt{{[]byte{1}, unavailable}},
+			want:       []byte{1},
+			wantCode:   codes.Unavailable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			addr, _ := startPublisher(t, "", func(ctx context.Context, _ any, send func(*pb.MessageWrapper) error) error {
+				n := int(calls.Add(1)) - 1
+				if n >= len(tt.attempts) {
+					t.Errorf("stream opened %d times, want %d", n+1, len(tt.attempts))
+					return idle(ctx)
+				}
+				for _, sig := range tt.attempts[n].signatures {
+					if err := send(txMessage(1, []byte{sig})); err != nil {
+						return err
+					}
+				}
+				if tt.attempts[n].err == nil {
+					return idle(ctx)
+				}
+				return tt.attempts[n].err
+			})
+			c, err := NewClient(Config{ServerAddr: addr, MaxRetries: tt.maxRetries, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			s, err := c.SubscribeToTransactions(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			var got []byte
+			for range tt.want {
+				tx, err := s.Recv()
+				if err != nil {
+					t.Fatalf("Recv after %v: %v", got, err)
+				}
+				got = append(got, tx.Signature...)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("received %v, want %v", got, tt.want)
+			}
+			if tt.wantCode != codes.OK {
+				if _, err := s.Recv(); status.Code(err) != tt.wantCode {
+					t.Errorf("final Recv error = %v, want code %v", err, tt.wantCode)
+				}
+			}
+			if got := statusTypes(c); !slices.Equal(got, tt.wantStatus) {
+				t.Errorf("status events %v, want %v", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	short := &Client{initialBackoff: 100 * time.Millisecond, maxBackoff: 5 * time.Second}
+	long := &Client{initialBackoff: time.Minute, maxBackoff: 10 * time.Minute}
+
+	tests := []struct {
+		c       *Client
+		attempt int
+		ceiling time.Duration
+	}{
+		{short, 1, 100 * time.Millisecond},
+		{short, 2, 200 * time.Millisecond},
+		{short, 3, 400 * time.Millisecond},
+		{short, 6, 3200 * time.Millisecond},
+		{short, 7, 5 * time.Second},
+		{short, 40, 5 * time.Second},
+		{short, 1 << 20, 5 * time.Second},
+		{long, 2, 2 * time.Minute},
+		{long, 5, 10 * time.Minute},
+		{long, 32, 10 * time.Minute},
+		{long, 64, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		c := tt.c
+		seen := make(map[time.Duration]bool)
+		for range 200 {
+			d := c.backoff(tt.attempt)
+			if d < tt.ceiling/2 || d > tt.ceiling {
+				t.Fatalf("backoff(%d) = %v, want within [%v, %v]", tt.attempt, d, tt.ceiling/2, tt.ceiling)
+			}
+			seen[d] = true
+		}
+		if len(seen) < 2 {
+			t.Errorf("backoff(%d) returned %d distinct delays in 200 calls, want jitter", tt.attempt, len(seen))
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package thorclient
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mr-tron/base58"
+)
+
+// Limits are the per-token subscription quotas enforced before dialing.
+// Zero fields use DefaultLimits; negative fields disable the check.
+type Limits struct {
+	Total        int
+	Transactions int
+	Accounts     int
+	Slots        int
+	Wallets      int
+
+	WalletsPerRequest  int
+	AccountsPerRequest int
+}
+
+// DefaultLimits are the server's documented quotas
+var DefaultLimits = Limits{
+	Total:              6,
+	Transactions:       2,
+	Accounts:           5,
+	Slots:              2,
+	Wallets:            10,
+	WalletsPerRequest:  10,
+	AccountsPerRequest: 100,
+}
+
+func (l Limits) withDefaults() Limits {
+	def := func(v *int, d int) {
+		if *v == 0 {
+			*v = d
+		}
+	}
+	def(&l.Total, DefaultLimits.Total)
+	def(&l.Transactions, DefaultLimits.Transactions)
+	def(&l.Accounts, DefaultLimits.Accounts)
+	def(&l.Slots, DefaultLimits.Slots)
+	def(&l.Wallets, DefaultLimits.Wallets)
+	def(&l.WalletsPerRequest, DefaultLimits.WalletsPerRequest)
+	def(&l.AccountsPerRequest, DefaultLimits.AccountsPerRequest)
+	return l
+}
+
+// exceeds reports whether n is over limit, treating negative limits as unlimited
+func exceeds(n, limit int) bool {
+	return limit >= 0 && n > limit
+}
+
+// SubscriptionInfo describes an open subscription
+type SubscriptionInfo struct {
+	ID        uint64
+	Type      SubscriptionType
+	Addresses []string
+	Owners    []string
+	Opened    time.Time
+}
+
+// registry tracks the client's open subscriptions against its limits
+type registry struct {
+	mu     sync.Mutex
+	limits Limits
+	nextID uint64
+	open   map[uint64]SubscriptionInfo
+}
+
+func newRegistry(limits Limits) *registry {
+	return &registry{
+		limits: limits.withDefaults(),
+		open:   make(map[uint64]SubscriptionInfo),
+	}
+}
+
+// acquire records a new subscription if the quotas allow it
+func (r *registry) acquire(info SubscriptionInfo) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total, sameType := len(r.open), 0
+	for _, sub := range r.open {
+		if sub.Type == info.Type {
+			sameType++
+		}
+	}
+
+	if exceeds(total+1, r.limits.Total) {
+		return 0, localError(ErrSubscriptionLimitReached, "%d of %d subscriptions already open", total, r.limits.Total)
+	}
+
+	limit, sentinel := r.typeLimit(info.Type)
+	if exceeds(sameType+1, limit) {
+		return 0, localError(sentinel, "%s: %d of %d streams already open", info.Type, sameType, limit)
+	}
+
+	r.nextID++
+	info.ID = r.nextID
+	info.Opened = time.Now()
+	r.open[info.ID] = info
+	return info.ID, nil
+}
+
+// typeLimit returns the quota for streams of kind and the error reported when
+// it is reached
+func (r *registry) typeLimit(kind SubscriptionType) (int, *Error) {
+	switch kind {
+	case SubscriptionTransactions:
+		return r.limits.Transactions, ErrTransactionSubscriptionLimitReached
+	case SubscriptionAccounts:
+		return r.limits.Accounts, ErrAccountSubscriptionLimitReached
+	case SubscriptionSlots:
+		return r.limits.Slots, ErrSlotSubscriptionLimitReached
+	case SubscriptionWallets:
+		return r.limits.Wallets, ErrWalletSubscriptionLimitReached
+	default:
+		return -1, ErrSubscriptionLimitReached
+	}
+}
+
+// available returns how many more streams of kind the quotas allow, or -1 if
+// unlimited
+func (r *registry) available(kind SubscriptionType) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sameType := 0
+	for _, sub := range r.open {
+		if sub.Type == kind {
+			sameType++
+		}
+	}
+
+	free := -1
+	if r.limits.Total >= 0 {
+		free = max(r.limits.Total-len(r.open), 0)
+	}
+	if limit, _ := r.typeLimit(kind); limit >= 0 {
+		if left := max(limit-sameType, 0); free < 0 || left < free {
+			free = left
+		}
+	}
+	return free
+}
+
+func (r *registry) release(id uint64) {
+	r.mu.Lock()
+	delete(r.open, id)
+	r.mu.Unlock()
+}
+
+// Subscriptions returns a snapshot of the client's open subscriptions,
+// oldest first
+func (c *Client) Subscriptions() []SubscriptionInfo {
+	c.registry.mu.Lock()
+	subs := make([]SubscriptionInfo, 0, len(c.registry.open))
+	for _, sub := range c.registry.open {
+		sub.Addresses = slices.Clone(sub.Addresses)
+		sub.Owners = slices.Clone(sub.Owners)
+		subs = append(subs, sub)
+	}
+	c.registry.mu.Unlock()
+
+	slices.SortFunc(subs, func(a, b SubscriptionInfo) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return subs
+}
+
+// validateWallets checks a wallet subscription request against the limits
+func (r *registry) validateWallets(wallets []string) error {
+	if len(wallets) == 0 {
+		return localError(ErrEmptyWalletList, "no wallet addresses provided")
+	}
+	if exceeds(len(wallets), r.limits.WalletsPerRequest) {
+		return localError(ErrTooManyWalletAddresses, "%d wallets exceeds the limit of %d per request", len(wallets), r.limits.WalletsPerRequest)
+	}
+	for _, wallet := range wallets {
+		if !isValidAddress(wallet) {
+			return localError(ErrInvalidWalletAddress, "%q is not a base58 public key", wallet)
+		}
+	}
+	return nil
+}
+
+// validateAccounts checks an account subscription request against the limits
+func (r *registry) validateAccounts(accounts, owners []string) error {
+	if len(accounts) == 0 && len(owners) == 0 {
+		return localError(ErrEmptyAccountList, "no account or owner addresses provided")
+	}
+	if exceeds(len(accounts), r.limits.AccountsPerRequest) {
+		return localError(ErrTooManyAccountAddresses, "%d accounts exceeds the limit of %d per request", len(accounts), r.limits.AccountsPerRequest)
+	}
+	for _, address := range slices.Concat(accounts, owners) {
+		if !isValidAddress(address) {
+			return localError(ErrInvalidAccountAddress, "%q is not a base58 public key", address)
+		}
+	}
+	return nil
+}
+
+// isValidAddress reports whether address is a base58 encoded 32-byte key
+func isValidAddress(address string) bool {
+	key, err := base58.Decode(address)
+	return err == nil && len(key) == 32
+}
+
+// localError reports a quota or validation failure detected before dialing
+func localError(sentinel *Error, format string, args ...any) *Error {
+	return &Error{
+		Kind:   sentinel.Kind,
+		Code:   sentinel.Code,
+		Status: sentinel.Status,
+		Err:    fmt.Errorf("%s: %s", sentinel.Code, fmt.Sprintf(format, args...)),
+	}
+}
@@ -0,0 +1,185 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+func TestRegistryAcquire(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits Limits
+		open   []SubscriptionType
+		want   error // of the last acquire, nil if all succeed
+	}{
+		{
+			name: "default limits",
+			open: []SubscriptionType{SubscriptionTransactions, SubscriptionTransactions, SubscriptionSlots, SubscriptionAccounts, SubscriptionWallets, SubscriptionWallets},
+		},
+		{
+			name: "total limit",
+			open: []SubscriptionType{SubscriptionTransactions, SubscriptionTransactions, SubscriptionSlots, SubscriptionSlots, SubscriptionAccounts, SubscriptionWallets, SubscriptionWallets},
+			want: ErrSubscriptionLimitReached,
+		},
+		{
+			name: "transaction limit",
+			open: []SubscriptionType{SubscriptionTransactions, SubscriptionTransactions, SubscriptionTransactions},
+			want: ErrTransactionSubscriptionLimitReached,
+		},
+		{
+			name:   "slot limit",
+			limits: Limits{Slots: 1},
+			open:   []SubscriptionType{SubscriptionSlots, SubscriptionSlots},
+			want:   ErrSlotSubscriptionLimitReached,
+		},
+		{
+			name:   "account limit",
+			limits: Limits{Accounts: 1},
+			open:   []SubscriptionType{SubscriptionAccounts, SubscriptionAccounts},
+			want:   ErrAccountSubscriptionLimitReached,
+		},
+		{
+			name:   "wallet limit",
+			limits: Limits{Wallets: 2},
+			open:   []SubscriptionType{SubscriptionWallets, SubscriptionWallets, SubscriptionWallets},
+			want:   ErrWalletSubscriptionLimitReached,
+		},
+		{
+			name:   "unlimited",
+			limits: Limits{Total: -1, Transactions: -1},
+			open:   slices.Repeat([]SubscriptionType{SubscriptionTransactions}, 20),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRegistry(tt.limits)
+			var err error
+			for i, kind := range tt.open {
+				if _, err = r.acquire(SubscriptionInfo{Type: kind}); err != nil && i != len(tt.open)-1 {
+					t.Fatalf("acquire %d (%s) = %v", i, kind, err)
+				}
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("last acquire = %v, want %v", err, tt.want)
+			}
+			if err != nil && strings.Contains(err.Error(), "%!") {
+				t.Errorf("badly formatted error %q", err)
+			}
+		})
+	}
+}
+
+func TestRegistryAvailable(t *testing.T) {
+	r := newRegistry(Limits{})
+	if got := r.available(SubscriptionWallets); got != 6 {
+		t.Errorf("available wallets = %d, want 6 (total limit)", got)
+	}
+	if got := r.available(SubscriptionTransactions); got != 2 {
+		t.Errorf("available transactions = %d, want 2", got)
+	}
+
+	id, err := r.acquire(SubscriptionInfo{Type: SubscriptionTransactions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.acquire(SubscriptionInfo{Type: SubscriptionTransactions}); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.available(SubscriptionTransactions); got != 0 {
+		t.Errorf("available transactions = %d, want 0", got)
+	}
+	if got := r.available(SubscriptionWallets); got != 4 {
+		t.Errorf("available wallets = %d, want 4", got)
+	}
+
+	r.release(id)
+	if got := r.available(SubscriptionTransactions); got != 1 {
+		t.Errorf("available transactions after release = %d, want 1", got)
+	}
+	if unlimited := newRegistry(Limits{Total: -1, Wallets: -1}); unlimited.available(SubscriptionWallets) != -1 {
+		t.Error("available with no limits should be -1")
+	}
+}
+
+func TestRegistryValidate(t *testing.T) {
+	r := newRegistry(Limits{WalletsPerRequest: 2, AccountsPerRequest: 2})
+	valid := testWallet(1)
+
+	tests := []struct {
+		name  string
+		check func() error
+		want  error
+	}{
+		{"wallets", func() error { return r.validateWallets([]string{valid, testWallet(2)}) }, nil},
+		{"no wallets", func() error { return r.validateWallets(nil) }, ErrEmptyWalletList},
+		{"too many wallets", func() error { return r.validateWallets([]string{valid, valid, valid}) }, ErrTooManyWalletAddresses},
+		{"invalid wallet", func() error { return r.validateWallets([]string{"0OIl"}) }, ErrInvalidWalletAddress},
+		{"short wallet", func() error { return r.validateWallets([]string{"11111111"}) }, ErrInvalidWalletAddress},
+		{"accounts", func() error { return r.validateAccounts([]string{valid}, nil) }, nil},
+		{"owners only", func() error { return r.validateAccounts(nil, []string{valid}) }, nil},
+		{"no accounts or owners", func() error { return r.validateAccounts(nil, nil) }, ErrEmptyAccountList},
+		{"too many accounts", func() error { return r.validateAccounts([]string{valid, valid, valid}, nil) }, ErrTooManyAccountAddresses},
+		{"invalid owner", func() error { return r.validateAccounts([]string{valid}, []string{"not-base58"}) }, ErrInvalidAccountAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check()
+			if (err == nil) != (tt.want == nil) || (tt.want != nil && !errors.Is(err, tt.want)) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubscriptionsRelease(t *testing.T) {
+	addr, _ := startPublisher(t, "", func(ctx context.Context, _ any, _ func(*pb.MessageWrapper) error) error {
+		return idle(ctx)
+	})
+	c, err := NewClient(Config{ServerAddr: addr, Limits: Limits{Transactions: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := c.SubscribeToTransactions(ctx); err != nil {
+		t.Fatal(err)
+	}
+	wallets, err := c.SubscribeToWalletTransactions(context.Background(), []string{testWallet(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SubscribeToTransactions(context.Background()); !errors.Is(err, ErrTransactionSubscriptionLimitReached) {
+		t.Fatalf("second transaction subscription = %v, want ErrTransactionSubscriptionLimitReached", err)
+	}
+
+	subs := c.Subscriptions()
+	if len(subs) != 2 || subs[0].Type != SubscriptionTransactions || subs[1].Type != SubscriptionWallets {
+		t.Fatalf("Subscriptions() = %+v, want transactions then wallets", subs)
+	}
+	if !slices.Equal(subs[1].Addresses, []string{testWallet(1)}) || subs[1].Opened.IsZero() {
+		t.Errorf("wallet subscription %+v, want its address and open time", subs[1])
+	}
+	subs[1].Addresses[0] = "changed"
+	if c.Subscriptions()[1].Addresses[0] != testWallet(1) {
+		t.Error("Subscriptions() shares its addresses with the registry")
+	}
+
+	// Cancelling the context frees the quota
+	cancel()
+	eventually(t, "the transaction quota to be freed", func() bool { return len(c.Subscriptions()) == 1 })
+	s, err := c.SubscribeToTransactions(context.Background())
+	if err != nil {
+		t.Fatalf("subscribe after cancel: %v", err)
+	}
+	s.Close()
+	wallets.Close()
+	if subs := c.Subscriptions(); len(subs) != 0 {
+		t.Errorf("Subscriptions() after Close = %+v, want none", subs)
+	}
+}
@@ -0,0 +1,342 @@
+package thorclient
+
+import (
+	"context"
+	"iter"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// ShardedStream spreads an address set over as many subscriptions as the
+// per-request limits require and merges their events into one stream.
+type ShardedStream[T any] struct {
+	client   *Client
+	kind     SubscriptionType
+	perShard int
+	invalid  *Error
+	open     func(ctx context.Context, addresses []string) (shardStream[T], error)
+	key      KeyFunc[T]
+	seen     *recentSet
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	out    chan shardResult[T]
+
+	mu     sync.Mutex
+	shards []*addressShard[T]
+}
+
+// addressShard is one underlying subscription of a ShardedStream
+type addressShard[T any] struct {
+	addresses []string
+	stream    shardStream[T]
+	cancel    context.CancelFunc
+}
+
+// shardStream is a subscription whose addresses can be replaced in place,
+// implemented by WalletStream and AccountStream
+type shardStream[T any] interface {
+	Receiver[T]
+	Close()
+	LastMessage() time.Time
+	update(addresses []string) error
+}
+
+type shardResult[T any] struct {
+	event T
+	err   error
+}
+
+// SubscribeToWalletTransactionsSharded subscribes to any number of wallets,
+// using one wallet stream per WalletsPerRequest addresses. Transactions
+// touching wallets in several shards are delivered once.
+func (c *Client) SubscribeToWalletTransactionsSharded(ctx context.Context, wallets []string) (*ShardedStream[*pb.TransactionEvent], error) {
+	s := newShardedStream(ctx, c, SubscriptionWallets, c.registry.limits.WalletsPerRequest, ErrInvalidWalletAddress, SignatureKey,
+		func(ctx context.Context, addresses []string) (shardStream[*pb.TransactionEvent], error) {
+			return c.SubscribeToWalletTransactions(ctx, addresses)
+		})
+	if err := s.Add(wallets...); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// SubscribeToAccountUpdatesSharded subscribes to any number of accounts,
+// using one account stream per AccountsPerRequest addresses.
+func (c *Client) SubscribeToAccountUpdatesSharded(ctx context.Context, accounts []string) (*ShardedStream[*pb.SubscribeUpdateAccountInfo], error) {
+	s := newShardedStream(ctx, c, SubscriptionAccounts, c.registry.limits.AccountsPerRequest, ErrInvalidAccountAddress, AccountVersionKey,
+		func(ctx context.Context, addresses []string) (shardStream[*pb.SubscribeUpdateAccountInfo], error) {
+			return c.SubscribeToAccountUpdates(ctx, addresses, nil)
+		})
+	if err := s.Add(accounts...); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func newShardedStream[T any](ctx context.Context, c *Client, kind SubscriptionType, perShard int, invalid *Error, key KeyFunc[T],
+	open func(context.Context, []string) (shardStream[T], error)) *ShardedStream[T] {
+	if perShard <= 0 {
+		perShard = math.MaxInt
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &ShardedStream[T]{
+		client:   c,
+		kind:     kind,
+		perShard: perShard,
+		invalid:  invalid,
+		open:     open,
+		key:      key,
+		seen:     newRecentSet(dedupWindow),
+		ctx:      ctx,
+		cancel:   cancel,
+		out:      make(chan shardResult[T], 256),
+	}
+}
+
+// Recv blocks until the next event from any shard. A terminal error of one
+// shard closes the whole stream.
+func (s *ShardedStream[T]) Recv() (T, error) {
+	for {
+		select {
+		case r := <-s.out:
+			if r.err != nil {
+				s.Close()
+				return r.event, r.err
+			}
+			if !s.seen.add(s.key(r.event)) {
+				continue
+			}
+			return r.event, nil
+		case <-s.ctx.Done():
+			var zero T
+			return zero, s.ctx.Err()
+		}
+	}
+}
+
+// All returns an iterator over the merged events, see Stream.All
+func (s *ShardedStream[T]) All() iter.Seq2[T, error] {
+	return all(s)
+}
+
+// Addresses returns the subscribed addresses
+func (s *ShardedStream[T]) Addresses() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var addresses []string
+	for _, shard := range s.shards {
+		addresses = append(addresses, shard.addresses...)
+	}
+	return addresses
+}
+
+// LastMessage returns when any shard last received a message
+func (s *ShardedStream[T]) LastMessage() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var last time.Time
+	for _, shard := range s.shards {
+		if t := shard.stream.LastMessage(); t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
+
+// Add subscribes to additional addresses. New addresses fill shards with spare
+// capacity first; only the shards that change are re-subscribed. On error the
+// addresses applied so far remain subscribed.
+func (s *ShardedStream[T]) Add(addresses ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	known := make(map[string]bool)
+	for _, shard := range s.shards {
+		for _, address := range shard.addresses {
+			known[address] = true
+		}
+	}
+	var added []string
+	for _, address := range addresses {
+		if known[address] {
+			continue
+		}
+		if !isValidAddress(address) {
+			return localError(s.invalid, "%q is not a base58 public key", address)
+		}
+		known[address] = true
+		added = append(added, address)
+	}
+
+	// Check the quota up front rather than failing halfway. Each updated
+	// shard holds its old stream for the update overlap, so it needs a spare
+	// stream as much as a new shard does.
+	needed, overflow := 0, len(added)
+	for _, shard := range s.shards {
+		if room := s.perShard - len(shard.addresses); room > 0 && overflow > 0 {
+			needed++
+			overflow -= room
+		}
+	}
+	if overflow > 0 {
+		needed += (overflow + s.perShard - 1) / s.perShard
+	}
+	if err := s.reserve(needed, 0); err != nil {
+		return err
+	}
+
+	for _, shard := range s.shards {
+		room := min(s.perShard-len(shard.addresses), len(added))
+		if room <= 0 {
+			continue
+		}
+		if err := s.resubscribe(shard, slices.Concat(shard.addresses, added[:room])); err != nil {
+			return err
+		}
+		added = added[room:]
+	}
+	for len(added) > 0 {
+		n := min(s.perShard, len(added))
+		shard := &addressShard[T]{}
+		if err := s.resubscribe(shard, slices.Clone(added[:n])); err != nil {
+			return err
+		}
+		s.shards = append(s.shards, shard)
+		added = added[n:]
+	}
+	return nil
+}
+
+// Remove unsubscribes from addresses, re-subscribing only the shards that
+// held them and closing shards left empty
+func (s *ShardedStream[T]) Remove(addresses ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		removed[address] = true
+	}
+
+	remaining := make([][]string, len(s.shards))
+	updates, emptied := 0, 0
+	for i, shard := range s.shards {
+		remaining[i] = slices.DeleteFunc(slices.Clone(shard.addresses), func(a string) bool { return removed[a] })
+		switch {
+		case len(remaining[i]) == len(shard.addresses):
+		case len(remaining[i]) == 0:
+			emptied++
+		default:
+			updates++
+		}
+	}
+	// Updated shards overlap their old stream like in Add; the quota of the
+	// emptied shards is freed before they are updated
+	if err := s.reserve(updates, emptied); err != nil {
+		return err
+	}
+
+	kept := s.shards[:0]
+	var firstErr error
+	for i, shard := range s.shards {
+		if len(remaining[i]) == 0 {
+			shard.close()
+		}
+	}
+	for i, shard := range s.shards {
+		switch {
+		case len(remaining[i]) == 0:
+		case len(remaining[i]) == len(shard.addresses):
+			kept = append(kept, shard)
+		default:
+			if err := s.resubscribe(shard, remaining[i]); err != nil && firstErr == nil {
+				firstErr = err
+			}
+			kept = append(kept, shard)
+		}
+	}
+	clear(s.shards[len(kept):])
+	s.shards = kept
+	return firstErr
+}
+
+// reserve checks, before any shard changes, that needed more streams fit the
+// quota once freed streams are closed
+func (s *ShardedStream[T]) reserve(needed, freed int) error {
+	if needed == 0 {
+		return nil
+	}
+	if free := s.client.registry.available(s.kind); free >= 0 && needed > free+freed {
+		_, sentinel := s.client.registry.typeLimit(s.kind)
+		return localError(sentinel, "address change needs %d spare %s streams, %d available", needed, s.kind, free+freed)
+	}
+	return nil
+}
+
+// Close ends all shards
+func (s *ShardedStream[T]) Close() {
+	s.cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, shard := range s.shards {
+		shard.close()
+	}
+}
+
+// resubscribe points the shard at addresses, updating its subscription in
+// place or opening one for a new shard
+func (s *ShardedStream[T]) resubscribe(shard *addressShard[T], addresses []string) error {
+	if shard.stream != nil {
+		if err := shard.stream.update(addresses); err != nil {
+			return err
+		}
+		shard.addresses = addresses
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	stream, err := s.open(ctx, addresses)
+	if err != nil {
+		cancel()
+		return err
+	}
+	shard.addresses, shard.stream, shard.cancel = addresses, stream, cancel
+	go s.pump(ctx, stream)
+	return nil
+}
+
+// pump forwards events of one shard until it is closed or fails
+func (s *ShardedStream[T]) pump(ctx context.Context, stream shardStream[T]) {
+	for {
+		event, err := stream.Recv()
+		if err != nil && ctx.Err() != nil {
+			// Closed by Remove or Close
+			return
+		}
+		select {
+		case s.out <- shardResult[T]{event: event, err: err}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (sh *addressShard[T]) close() {
+	if sh.stream != nil {
+		sh.cancel()
+		sh.stream.Close()
+	}
+}
@@ -0,0 +1,226 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// testWallets returns n distinct valid addresses
+func testWallets(n int) []string {
+	wallets := make([]string, n)
+	for i := range wallets {
+		wallets[i] = testWallet(byte(i + 1))
+	}
+	return wallets
+}
+
+// walletRequests records the wallets of each wallet stream opened on a server
+type walletRequests struct {
+	mu   sync.Mutex
+	reqs [][]string
+}
+
+func (r *walletRequests) add(req any) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reqs = append(r.reqs, req.(*pb.SubscribeWalletRequest).WalletAddress)
+	return len(r.reqs) - 1
+}
+
+func (r *walletRequests) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sizes []int
+	for _, wallets := range r.reqs {
+		sizes = append(sizes, len(wallets))
+	}
+	return sizes
+}
+
+// shardSizes returns the number of addresses in each shard of s
+func shardSizes[T any](s *ShardedStream[T]) []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var sizes []int
+	for _, shard := range s.shards {
+		sizes = append(sizes, len(shard.addresses))
+	}
+	return sizes
+}
+
+func TestShardedSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		wallets []string
+		want    []int // wallets per shard
+		wantErr error
+	}{
+		{name: "one shard", wallets: testWallets(10), want: []int{10}},
+		{name: "partial last shard", wallets: testWallets(25), want: []int{10, 10, 5}},
+		{name: "duplicates", wallets: slices.Concat(testWallets(12), testWallets(12)), want: []int{10, 2}},
+		{name: "up to the stream limit", wallets: testWallets(60), want: []int{10, 10, 10, 10, 10, 10}},
+		{name: "past the stream limit", wallets: testWallets(61), wantErr: ErrWalletSubscriptionLimitReached},
+		{name: "invalid address", wallets: append(testWallets(15), "invalid"), wantErr: ErrInvalidWalletAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests walletRequests
+			addr, _ := startPublisher(t, "", func(ctx context.Context, req any, _ func(*pb.MessageWrapper) error) error {
+				requests.add(req)
+				return idle(ctx)
+			})
+			c, err := NewClient(Config{ServerAddr: addr})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			s, err := c.SubscribeToWalletTransactionsSharded(context.Background(), tt.wallets)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("subscribe = %v, want %v", err, tt.wantErr)
+				}
+				// Rejected before any stream was opened
+				if sizes := requests.sizes(); len(sizes) != 0 {
+					t.Errorf("opened streams of %v wallets, want none", sizes)
+				}
+				if subs := c.Subscriptions(); len(subs) != 0 {
+					t.Errorf("%d subscriptions left open", len(subs))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			if got := shardSizes(s); !slices.Equal(got, tt.want) {
+				t.Errorf("shards of %v wallets, want %v", got, tt.want)
+			}
+			eventually(t, "the shard streams", func() bool { return len(requests.sizes()) == len(tt.want) })
+			// The server may see the shard streams in any order
+			if got := requests.sizes(); !slices.Equal(slices.Sorted(slices.Values(got)), slices.Sorted(slices.Values(tt.want))) {
+				t.Errorf("server streams of %v wallets, want %v", got, tt.want)
+			}
+			if got := s.Addresses(); len(got) != len(slices.Compact(slices.Sorted(slices.Values(tt.wallets)))) {
+				t.Errorf("Addresses() has %d wallets, want each of %d once", len(got), len(tt.wallets))
+			}
+		})
+	}
+}
+
+func TestShardedDedup(t *testing.T) {
+	var requests walletRequests
+	addr, _ := startPublisher(t, "", func(ctx context.Context, req any, send func(*pb.MessageWrapper) error) error {
+		n := requests.add(req)
+		// Every shard sees the shared transaction and one of its own
+		for _, sig := range []byte{42, byte(100 + n)} {
+			if err := send(txMessage(1, []byte{sig})); err != nil {
+				return err
+			}
+		}
+		return idle(ctx)
+	})
+	c, err := NewClient(Config{ServerAddr: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s, err := c.SubscribeToWalletTransactionsSharded(context.Background(), testWallets(25))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var got []byte
+	for range 4 {
+		tx, err := s.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, tx.Signature...)
+	}
+	slices.Sort(got)
+	if want := []byte{42, 100, 101, 102}; !slices.Equal(got, want) {
+		t.Errorf("received %v, want %v", got, want)
+	}
+
+	extra := make(chan byte, 1)
+	go func() {
+		if tx, err := s.Recv(); err == nil {
+			extra <- tx.Signature[0]
+		}
+	}()
+	select {
+	case sig := <-extra:
+		t.Errorf("received transaction %d twice", sig)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestShardedAddRemove(t *testing.T) {
+	addr, _ := startPublisher(t, "", func(ctx context.Context, _ any, _ func(*pb.MessageWrapper) error) error {
+		return idle(ctx)
+	})
+	c, err := NewClient(Config{ServerAddr: addr, UpdateOverlap: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	wallets := testWallets(60)
+	s, err := c.SubscribeToWalletTransactionsSharded(context.Background(), wallets[:50])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// settle waits for the overlapping streams of updated shards to close
+	settle := func(shards int) {
+		t.Helper()
+		eventually(t, "updated shards to close their old streams", func() bool { return len(c.Subscriptions()) == shards })
+	}
+	check := func(step string, err, wantErr error, want []int) {
+		t.Helper()
+		if !errors.Is(err, wantErr) || (err != nil) != (wantErr != nil) {
+			t.Fatalf("%s: error = %v, want %v", step, err, wantErr)
+		}
+		if got := shardSizes(s); !slices.Equal(got, want) {
+			t.Fatalf("%s: shards of %v wallets, want %v", step, got, want)
+		}
+	}
+
+	// A new shard takes the last spare stream
+	check("add a shard", s.Add(wallets[50]), nil, []int{10, 10, 10, 10, 10, 1})
+	// Filling it needs a spare stream for the update overlap
+	check("fill the last shard", s.Add(wallets[51:55]...), ErrWalletSubscriptionLimitReached, []int{10, 10, 10, 10, 10, 1})
+
+	check("remove a shard", s.Remove(wallets[50]), nil, []int{10, 10, 10, 10, 10})
+	settle(5)
+	check("shrink a shard", s.Remove(wallets[0]), nil, []int{9, 10, 10, 10, 10})
+	if n := len(c.Subscriptions()); n != 6 {
+		t.Errorf("%d subscriptions during the update overlap, want 6", n)
+	}
+	settle(5)
+
+	check("grow a shard", s.Add(wallets[0]), nil, []int{10, 10, 10, 10, 10})
+	settle(5)
+	check("add a shard again", s.Add(wallets[50]), nil, []int{10, 10, 10, 10, 10, 1})
+	// Emptying the last shard frees the stream the first one needs to update
+	check("remove from two shards", s.Remove(wallets[50], wallets[1]), nil, []int{9, 10, 10, 10, 10})
+	settle(5)
+	if got := s.Addresses(); len(got) != 49 || slices.Contains(got, wallets[1]) || slices.Contains(got, wallets[50]) {
+		t.Errorf("Addresses() = %d wallets after removing two, want 49", len(got))
+	}
+
+	s.Close()
+	if n := len(c.Subscriptions()); n != 0 {
+		t.Errorf("%d subscriptions after Close, want none", n)
+	}
+}
@@ -0,0 +1,428 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// Commitment is the confirmation level of a slot, the Status of a
+// SlotStatusEvent
+type Commitment int32
+
+const (
+	CommitmentProcessed Commitment = iota
+	CommitmentConfirmed
+	CommitmentRooted
+
+	// CommitmentFinalized is the RPC name for CommitmentRooted
+	CommitmentFinalized = CommitmentRooted
+)
+
+func (c Commitment) String() string {
+	switch c {
+	case CommitmentProcessed:
+		return "processed"
+	case CommitmentConfirmed:
+		return "confirmed"
+	case CommitmentRooted:
+		return "rooted"
+	default:
+		return fmt.Sprintf("Commitment(%d)", int32(c))
+	}
+}
+
+var (
+	// ErrSlotSkipped means a later slot was rooted without the slot, which
+	// was skipped by its leader or forked off
+	ErrSlotSkipped = errors.New("slot was skipped or forked off")
+	// ErrSlotUntracked means the slot is older than the tracker's history
+	ErrSlotUntracked = errors.New("slot is outside the tracked history")
+)
+
+const (
+	// slotStatusCompleted is the SlotStatusEvent status of a slot whose
+	// shreds were all received
+	slotStatusCompleted = 4
+	// slotStatusDead is the SlotStatusEvent status of a slot whose replay
+	// failed
+	slotStatusDead = 6
+)
+
+// slotHistory is the number of slots a SlotTracker keeps below the rooted tip
+const slotHistory = 1024
+
+// SlotInfo is what a SlotTracker knows about a slot
+type SlotInfo struct {
+	Slot        uint64
+	Parent      uint64
+	BlockHash   []byte
+	BlockHeight uint64
+	Commitment  Commitment
+	// Completed is set once the slot status reported all of the slot's
+	// shreds received
+	Completed bool
+	// Skipped is set once a later slot was rooted without this one, or the
+	// slot descends from such a slot or died in replay
+	Skipped bool
+}
+
+// SlotTracker follows a slot status stream, keeping the processed, confirmed
+// and rooted tips and the recent slots' parents, block hashes and heights.
+// Reaching a commitment implies it for the slot's known ancestors.
+type SlotTracker struct {
+	mu    sync.Mutex
+	slots map[uint64]*SlotInfo
+	tips  [CommitmentRooted + 1]uint64
+	// judged is the lowest slot from which every slot up to the rooted tip
+	// is known to be rooted or skipped, 0 before the first root
+	judged  uint64
+	waiters map[*slotWaiter]struct{}
+	changed chan struct{}
+	// orphaned holds the seen slots abandoned for a competing fork
+	orphaned map[uint64]struct{}
+	// completed holds the slots reported complete, which may precede
+	// their first commitment
+	completed     map[uint64]struct{}
+	rootListeners []func(root uint64, orphaned []uint64)
+
+	done chan struct{}
+	err  error
+}
+
+type slotWaiter struct {
+	slot       uint64
+	commitment Commitment
+	ready      chan error
+}
+
+// TrackSlots starts a SlotTracker fed by stream. It stops when the stream
+// ends or ctx is done; pending waits then fail.
+func TrackSlots(ctx context.Context, stream Receiver[*pb.SlotStatusEvent]) *SlotTracker {
+	t := &SlotTracker{
+		slots:     make(map[uint64]*SlotInfo),
+		waiters:   make(map[*slotWaiter]struct{}),
+		changed:   make(chan struct{}),
+		orphaned:  make(map[uint64]struct{}),
+		completed: make(map[uint64]struct{}),
+		done:      make(chan struct{}),
+	}
+	go func() {
+		t.stop(t.run(ctx, stream))
+	}()
+	return t
+}
+
+func (t *SlotTracker) run(ctx context.Context, stream Receiver[*pb.SlotStatusEvent]) error {
+	for {
+		ev, err := stream.Recv()
+		if err != nil {
+			if IsStreamDone(err) || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		t.observe(ev)
+	}
+}
+
+func (t *SlotTracker) stop(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.err = err
+	close(t.done)
+	t.wake()
+}
+
+// Done is closed when the tracker has stopped
+func (t *SlotTracker) Done() <-chan struct{} {
+	return t.done
+}
+
+// Err returns the stream error that stopped the tracker, or nil
+func (t *SlotTracker) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// Tip returns the highest slot that reached commitment c
+func (t *SlotTracker) Tip(c Commitment) uint64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if c < 0 || int(c) >= len(t.tips) {
+		return 0
+	}
+	return t.tips[c]
+}
+
+// StatusOf returns the commitment of slot, reporting false if the slot has
+// not been seen or is no longer tracked
+func (t *SlotTracker) StatusOf(slot uint64) (Commitment, bool) {
+	info, ok := t.Slot(slot)
+	return info.Commitment, ok && !info.Skipped
+}
+
+// Slot returns what is known about slot
+func (t *SlotTracker) Slot(slot uint64) (SlotInfo, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	info, ok := t.slots[slot]
+	if !ok {
+		return SlotInfo{}, false
+	}
+	out := *info
+	out.BlockHash = slices.Clone(info.BlockHash)
+	_, out.Completed = t.completed[slot]
+	out.Skipped = t.skipped(slot)
+	return out, true
+}
+
+// WaitForCommitment blocks until slot reaches commitment c. It fails with
+// ErrSlotSkipped if the slot will never reach it, ErrSlotUntracked if the
+// slot is too old to tell, or the tracker's error once it stops.
+func (t *SlotTracker) WaitForCommitment(ctx context.Context, slot uint64, c Commitment) error {
+	t.mu.Lock()
+	if err, ok := t.resolve(slot, c); ok {
+		t.mu.Unlock()
+		return err
+	}
+	w := &slotWaiter{slot: slot, commitment: c, ready: make(chan error, 1)}
+	t.waiters[w] = struct{}{}
+	t.mu.Unlock()
+
+	select {
+	case err := <-w.ready:
+		return err
+	case <-ctx.Done():
+		t.mu.Lock()
+		delete(t.waiters, w)
+		t.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// observe applies one slot status event
+func (t *SlotTracker) observe(ev *pb.SlotStatusEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if ev.Slot < t.judged {
+		return
+	}
+	c := Commitment(ev.Status)
+	if c < 0 || c > CommitmentRooted {
+		// Replay progress statuses say nothing about commitment. Completion
+		// is kept for block assembly, and a dead slot will never be processed.
+		if ev.Status == slotStatusCompleted {
+			t.completed[ev.Slot] = struct{}{}
+			t.wake()
+		}
+		if _, orphaned := t.orphaned[ev.Slot]; ev.Status == slotStatusDead && t.slots[ev.Slot] == nil && !orphaned {
+			t.orphan([]uint64{ev.Slot})
+			t.wake()
+		}
+		return
+	}
+	info := t.slots[ev.Slot]
+	if info == nil {
+		info = &SlotInfo{Slot: ev.Slot}
+		t.slots[ev.Slot] = info
+	}
+	if ev.Parent != 0 {
+		info.Parent = ev.Parent
+		_, orphanedParent := t.orphaned[ev.Parent]
+		if _, orphaned := t.orphaned[ev.Slot]; orphanedParent && !orphaned {
+			// Built on an abandoned fork
+			t.orphan([]uint64{ev.Slot})
+		}
+	}
+	if len(ev.BlockHash) > 0 {
+		info.BlockHash = ev.BlockHash
+	}
+	if ev.BlockHeight != 0 {
+		info.BlockHeight = ev.BlockHeight
+	}
+
+	for s := info; s != nil && s.Commitment < c; s = t.slots[s.Parent] {
+		s.Commitment = c
+	}
+	prevRoot := t.tips[CommitmentRooted]
+	for level := CommitmentProcessed; level <= c; level++ {
+		t.tips[level] = max(t.tips[level], ev.Slot)
+	}
+	if c == CommitmentRooted && ev.Slot > prevRoot {
+		t.advanceRoot(info, prevRoot)
+	}
+	t.wake()
+}
+
+// advanceRoot updates the range of slots whose fate is known after root
+// replaced prevRoot as the rooted tip, and prunes history
+func (t *SlotTracker) advanceRoot(root *SlotInfo, prevRoot uint64) {
+	// Walk down the new root's ancestry to the previous root, if known
+	s := root
+	for s.Parent > prevRoot {
+		p := t.slots[s.Parent]
+		if p == nil {
+			break
+		}
+		s = p
+	}
+	switch {
+	case t.judged != 0 && s.Parent != 0 && s.Parent <= prevRoot:
+		// Linked to the judged range
+	case s.Parent != 0:
+		// Everything between the missing parent and s was skipped
+		t.judged = s.Parent + 1
+	default:
+		t.judged = s.Slot
+	}
+
+	if root.Slot > slotHistory {
+		floor := root.Slot - slotHistory
+		for slot := range t.slots {
+			if slot < floor {
+				delete(t.slots, slot)
+			}
+		}
+		for slot := range t.orphaned {
+			if slot < floor {
+				delete(t.orphaned, slot)
+			}
+		}
+		for slot := range t.completed {
+			if slot < floor {
+				delete(t.completed, slot)
+			}
+		}
+		t.judged = max(t.judged, floor)
+	}
+
+	var orphans []uint64
+	for slot, info := range t.slots {
+		if _, done := t.orphaned[slot]; !done && t.abandoned(info, root.Slot) {
+			orphans = append(orphans, slot)
+		}
+	}
+	slices.Sort(orphans)
+	t.orphan(orphans)
+}
+
+// abandoned reports whether a seen slot is off the chain leading to root
+func (t *SlotTracker) abandoned(info *SlotInfo, root uint64) bool {
+	if info.Slot < t.judged {
+		return false
+	}
+	if info.Slot <= root {
+		return info.Commitment < CommitmentRooted
+	}
+	// Above the root, find where the slot's ancestry meets the rooted range
+	for s := info; ; {
+		if _, ok := t.orphaned[s.Parent]; ok {
+			return true
+		}
+		if s.Parent <= root {
+			parent := t.slots[s.Parent]
+			return s.Parent >= t.judged && (parent == nil || parent.Commitment < CommitmentRooted)
+		}
+		if s = t.slots[s.Parent]; s == nil {
+			return false
+		}
+	}
+}
+
+// orphan records slots as abandoned and notifies the root listeners, which
+// are also told about root advances without orphans
+func (t *SlotTracker) orphan(slots []uint64) {
+	for _, slot := range slots {
+		t.orphaned[slot] = struct{}{}
+	}
+	for _, listener := range t.rootListeners {
+		listener(t.tips[CommitmentRooted], slots)
+	}
+}
+
+// onRoot registers fn to be called, with the tracker locked, whenever the
+// rooted tip advances or slots are abandoned
+func (t *SlotTracker) onRoot(fn func(root uint64, orphaned []uint64)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rootListeners = append(t.rootListeners, fn)
+}
+
+// skipped reports whether slot is known to have been passed over by a root or
+// built on an abandoned fork
+func (t *SlotTracker) skipped(slot uint64) bool {
+	if _, ok := t.orphaned[slot]; ok {
+		return true
+	}
+	if t.judged == 0 || slot < t.judged || slot > t.tips[CommitmentRooted] {
+		return false
+	}
+	info := t.slots[slot]
+	return info == nil || info.Commitment < CommitmentRooted
+}
+
+// resolve returns the outcome of waiting for slot to reach c, reporting false
+// while it is still pending
+func (t *SlotTracker) resolve(slot uint64, c Commitment) (error, bool) {
+	if info := t.slots[slot]; info != nil && info.Commitment >= c {
+		return nil, true
+	}
+	switch {
+	case t.skipped(slot):
+		return ErrSlotSkipped, true
+	case slot <= t.tips[CommitmentRooted]:
+		return ErrSlotUntracked, true
+	}
+	select {
+	case <-t.done:
+		if t.err != nil {
+			return fmt.Errorf("slot tracker stopped: %w", t.err), true
+		}
+		return fmt.Errorf("slot tracker stopped: %w", ErrStreamClosed), true
+	default:
+		return nil, false
+	}
+}
+
+// changes returns a channel closed at the tracker's next change
+func (t *SlotTracker) changes() <-chan struct{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.changed
+}
+
+// outcome is resolve for callers not holding the lock
+func (t *SlotTracker) outcome(slot uint64, c Commitment) (error, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.resolve(slot, c)
+}
+
+// completedSlot reports whether slot was reported complete, for callers not
+// holding the lock
+func (t *SlotTracker) completedSlot(slot uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, ok := t.completed[slot]
+	return ok
+}
+
+// wake resolves the waiters that no longer need to wait and signals the change
+func (t *SlotTracker) wake() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+	for w := range t.waiters {
+		if err, ok := t.resolve(w.slot, w.commitment); ok {
+			w.ready <- err
+			delete(t.waiters, w)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// idleReceiver blocks until its context is done, so that a test can feed a
+// SlotTracker through observe without racing its run loop
+type idleReceiver[T any] struct {
+	ctx context.Context
+}
+
+func (r idleReceiver[T]) Recv() (T, error) {
+	<-r.ctx.Done()
+	var zero T
+	return zero, r.ctx.Err()
+}
+
+// slotUpdate is a SlotStatusEvent in a test's event sequence
+type slotUpdate struct {
+	slot, parent uint64
+	status       int32
+}
+
+func (e slotUpdate) proto() *pb.SlotStatusEvent {
+	return &pb.SlotStatusEvent{Slot: e.slot, Parent: e.parent, Status: e.status}
+}
+
+// newTestTracker returns a tracker fed only through observe and a function
+// stopping it
+func newTestTracker(t *testing.T) (*SlotTracker, func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker := TrackSlots(ctx, idleReceiver[*pb.SlotStatusEvent]{ctx})
+	t.Cleanup(cancel)
+	return tracker, func() {
+		cancel()
+		<-tracker.Done()
+	}
+}
+
+func TestSlotTracker(t *testing.T) {
+	const (
+		processed = int32(CommitmentProcessed)
+		confirmed = int32(CommitmentConfirmed)
+		rooted    = int32(CommitmentRooted)
+		completed = slotStatusCompleted
+		dead      = slotStatusDead
+	)
+	type outcome struct {
+		slot       uint64
+		commitment Commitment
+		err        error // nil when the commitment was reached
+		pending    bool
+	}
+
+	tests := []struct {
+		name     string
+		events   []slotUpdate
+		tips     [3]uint64
+		outcomes []outcome
+		// completed are the slots reported complete
+		completed []uint64
+	}{
+		{
+			name:   "confirmation implies ancestors",
+			events: []slotUpdate{{100, 99, processed}, {101, 100, processed}, {102, 101, confirmed}},
+			tips:   [3]uint64{102, 102, 0},
+			outcomes: []outcome{
+				{100, CommitmentConfirmed, nil, false},
+				{101, CommitmentConfirmed, nil, false},
+				{101, CommitmentRooted, nil, true},
+				{103, CommitmentProcessed, nil, true},
+			},
+		},
+		{
+			name: "root abandons competing fork",
+			events: []slotUpdate{
+				{100, 99, processed}, {101, 100, processed}, {102, 100, processed}, {103, 101, processed},
+				{100, 0, rooted}, {102, 0, rooted},
+			},
+			tips: [3]uint64{103, 102, 102},
+			outcomes: []outcome{
+				{101, CommitmentProcessed, nil, false},
+				{101, CommitmentConfirmed, ErrSlotSkipped, false},
+				{103, CommitmentConfirmed, ErrSlotSkipped, false},
+				{102, CommitmentRooted, nil, false},
+			},
+		},
+		{
+			name:   "child of abandoned fork",
+			events: []slotUpdate{{100, 99, processed}, {101, 100, processed}, {102, 100, rooted}, {104, 101, processed}},
+			tips:   [3]uint64{104, 102, 102},
+			outcomes: []outcome{
+				{101, CommitmentRooted, ErrSlotSkipped, false},
+				{104, CommitmentRooted, ErrSlotSkipped, false},
+			},
+		},
+		{
+			name:   "slot missing below root was skipped",
+			events: []slotUpdate{{100, 99, rooted}, {102, 100, rooted}},
+			tips:   [3]uint64{102, 102, 102},
+			outcomes: []outcome{
+				{101, CommitmentProcessed, ErrSlotSkipped, false},
+				{50, CommitmentProcessed, ErrSlotUntracked, false},
+			},
+		},
+		{
+			name:   "dead slot",
+			events: []slotUpdate{{100, 99, processed}, {101, 0, dead}},
+			tips:   [3]uint64{100, 0, 0},
+			outcomes: []outcome{
+				{101, CommitmentProcessed, ErrSlotSkipped, false},
+			},
+		},
+		{
+			name:   "replay progress is not commitment",
+			events: []slotUpdate{{100, 99, 3}, {100, 99, completed}, {100, 99, 5}},
+			tips:   [3]uint64{0, 0, 0},
+			outcomes: []outcome{
+				{100, CommitmentProcessed, nil, true},
+			},
+			completed: []uint64{100},
+		},
+		{
+			name:   "dead status of a processed slot is ignored",
+			events: []slotUpdate{{100, 99, processed}, {100, 0, dead}, {100, 0, confirmed}},
+			tips:   [3]uint64{100, 100, 0},
+			outcomes: []outcome{
+				{100, CommitmentConfirmed, nil, false},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, _ := newTestTracker(t)
+			for _, ev := range tt.events {
+				tracker.observe(ev.proto())
+			}
+
+			for c, want := range tt.tips {
+				if got := tracker.Tip(Commitment(c)); got != want {
+					t.Errorf("Tip(%v) = %d, want %d", Commitment(c), got, want)
+				}
+			}
+			for _, o := range tt.outcomes {
+				err, decided := tracker.outcome(o.slot, o.commitment)
+				switch {
+				case o.pending && decided:
+					t.Errorf("slot %d %v: decided with %v, want pending", o.slot, o.commitment, err)
+				case !o.pending && !decided:
+					t.Errorf("slot %d %v: pending, want %v", o.slot, o.commitment, o.err)
+				case !errors.Is(err, o.err):
+					t.Errorf("slot %d %v: %v, want %v", o.slot, o.commitment, err, o.err)
+				}
+			}
+			for _, slot := range tt.completed {
+				if !tracker.completedSlot(slot) {
+					t.Errorf("slot %d not recorded as complete", slot)
+				}
+			}
+		})
+	}
+}
+
+func TestWaitForCommitment(t *testing.T) {
+	tracker, stop := newTestTracker(t)
+	tracker.observe(slotUpdate{100, 99, 0}.proto())
+
+	confirmed := make(chan error, 1)
+	go func() { confirmed <- tracker.WaitForCommitment(context.Background(), 100, CommitmentConfirmed) }()
+	stopped := make(chan error, 1)
+	go func() { stopped <- tracker.WaitForCommitment(context.Background(), 200, CommitmentProcessed) }()
+
+	tracker.observe(slotUpdate{101, 100, 1}.proto())
+	select {
+	case err := <-confirmed:
+		if err != nil {
+			t.Errorf("WaitForCommitment(100, confirmed) = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitForCommitment(100, confirmed) did not return after a confirmed child")
+	}
+
+	stop()
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, ErrStreamClosed) {
+			t.Errorf("WaitForCommitment after stop = %v, want ErrStreamClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("WaitForCommitment did not return after the tracker stopped")
+	}
+}
@@ -0,0 +1,213 @@
+package thorclient
+
+import (
+	"context"
+	"iter"
+	"sync"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// Stream delivers the events of one subscription decoded to T. Messages of
+// other MessageWrapper variants are skipped.
+type Stream[T any] struct {
+	source     *resumableStream
+	extract    extractFunc[T]
+	streamType pb.StreamType
+
+	// Updatable streams receive through pumps so that an old and a new
+	// subscription can overlap while the filter changes
+	mu     sync.Mutex
+	ctx    context.Context
+	cancel context.CancelFunc
+	events chan sourceResult
+	key    KeyFunc[T]
+	seen   *recentSet
+}
+
+// sourceResult is one message or terminal error pumped from a subscription
+type sourceResult struct {
+	msg    *pb.MessageWrapper
+	err    error
+	source *resumableStream
+}
+
+type (
+	TransactionStream = Stream[*pb.TransactionEvent]
+	SlotStream        = Stream[*pb.SlotStatusEvent]
+	ThorStream        = Stream[*pb.MessageWrapper]
+)
+
+// extractFunc picks the payload out of a message, reporting false for
+// variants the stream does not deliver
+type extractFunc[T any] func(msg *pb.MessageWrapper) (T, pb.StreamType, bool)
+
+func newStream[T any](source *resumableStream, extract extractFunc[T]) *Stream[T] {
+	return &Stream[T]{source: source, extract: extract}
+}
+
+// newUpdatableStream returns a stream whose subscription can be replaced.
+// Sources must be opened with ctx; cancel ends all of them.
+func newUpdatableStream[T any](ctx context.Context, cancel context.CancelFunc, source *resumableStream, extract extractFunc[T], key KeyFunc[T]) *Stream[T] {
+	s := &Stream[T]{
+		source:  source,
+		extract: extract,
+		ctx:     ctx,
+		cancel:  cancel,
+		events:  make(chan sourceResult, 64),
+		key:     key,
+		seen:    newRecentSet(dedupWindow),
+	}
+	go s.pump(source)
+	return s
+}
+
+// Recv blocks until the next event is received
+func (s *Stream[T]) Recv() (T, error) {
+	for {
+		msg, err := s.next()
+		if err != nil {
+			var zero T
+			return zero, err
+		}
+		event, streamType, ok := s.extract(msg)
+		if !ok || (s.seen != nil && !s.seen.add(s.key(event))) {
+			continue
+		}
+		s.streamType = streamType
+		return event, nil
+	}
+}
+
+// next receives the next message of the current subscription
+func (s *Stream[T]) next() (*pb.MessageWrapper, error) {
+	if s.events == nil {
+		return s.source.Recv()
+	}
+	for {
+		select {
+		case r := <-s.events:
+			if r.err != nil && r.source != s.current() {
+				// A subscription retired by replace
+				continue
+			}
+			return r.msg, r.err
+		case <-s.ctx.Done():
+			return nil, s.ctx.Err()
+		}
+	}
+}
+
+// Close ends the subscription and frees its quota. Subsequent Recv calls
+// return an error for which IsStreamDone is true.
+func (s *Stream[T]) Close() {
+	if s.cancel != nil {
+		s.cancel()
+		// Free the current quota now rather than when the context watcher runs
+		s.current().close()
+		return
+	}
+	s.source.close()
+}
+
+func (s *Stream[T]) current() *resumableStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.source
+}
+
+// replace switches an updatable stream to the subscription returned by open.
+// The old subscription keeps delivering for the client's UpdateOverlap so no
+// events are lost while the new one starts; duplicates are dropped by key.
+// The overlap needs quota for one more subscription of the stream's type; if
+// open fails, including on a limit error, the old subscription stays in place.
+func (s *Stream[T]) replace(open func(ctx context.Context) (*resumableStream, error)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.source
+	next, err := open(s.ctx)
+	if err != nil {
+		return err
+	}
+
+	next.lastMessage.CompareAndSwap(0, old.lastMessage.Load())
+	s.source = next
+	go s.pump(next)
+	time.AfterFunc(next.client.updateOverlap, old.close)
+	return nil
+}
+
+// pump forwards the messages of source until it fails or the stream is closed
+func (s *Stream[T]) pump(source *resumableStream) {
+	for {
+		msg, err := source.Recv()
+		select {
+		case s.events <- sourceResult{msg: msg, err: err, source: source}:
+		case <-s.ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// LastMessage returns when the stream last received a message, or the zero
+// time if it has not received any yet
+func (s *Stream[T]) LastMessage() time.Time {
+	return s.current().lastReceived()
+}
+
+// StreamType returns the stream type reported with the last received
+// transaction. It is STREAM_TYPE_UNSPECIFIED for other events.
+func (s *Stream[T]) StreamType() pb.StreamType {
+	return s.streamType
+}
+
+// All returns an iterator over the stream's events. Iteration ends when the
+// stream is done; any other error is yielded as the final element.
+func (s *Stream[T]) All() iter.Seq2[T, error] {
+	return all(s)
+}
+
+// all iterates over the events of r, see Stream.All
+func all[T any](r Receiver[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			event, err := r.Recv()
+			if err != nil {
+				if !IsStreamDone(err) {
+					yield(event, err)
+				}
+				return
+			}
+			if !yield(event, nil) {
+				return
+			}
+		}
+	}
+}
+
+func transactionEvent(msg *pb.MessageWrapper) (*pb.TransactionEvent, pb.StreamType, bool) {
+	wrapper := msg.GetTransaction()
+	if wrapper.GetTransaction() == nil {
+		return nil, pb.StreamType_STREAM_TYPE_UNSPECIFIED, false
+	}
+	return wrapper.Transaction, wrapper.StreamType, true
+}
+
+func slotEvent(msg *pb.MessageWrapper) (*pb.SlotStatusEvent, pb.StreamType, bool) {
+	slot := msg.GetSlot()
+	return slot, pb.StreamType_STREAM_TYPE_UNSPECIFIED, slot != nil
+}
+
+func accountUpdate(msg *pb.MessageWrapper) (*pb.SubscribeUpdateAccountInfo, pb.StreamType, bool) {
+	account := msg.GetAccountUpdate()
+	return account, pb.StreamType_STREAM_TYPE_UNSPECIFIED, account != nil
+}
+
+func anyEvent(msg *pb.MessageWrapper) (*pb.MessageWrapper, pb.StreamType, bool) {
+	return msg, msg.GetTransaction().GetStreamType(), true
+}
@@ -0,0 +1,217 @@
+package thorclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// slotMessage wraps a slot status event
+func slotMessage(slot uint64, status int32) *pb.MessageWrapper {
+	return &pb.MessageWrapper{EventMessage: &pb.MessageWrapper_Slot{Slot: &pb.SlotStatusEvent{Slot: slot, Status: status}}}
+}
+
+// accountMessage wraps an account update of the pubkey made of the byte b
+func accountMessage(b byte, writeVersion uint64) *pb.MessageWrapper {
+	pubkey := make([]byte, 32)
+	pubkey[0] = b
+	return &pb.MessageWrapper{EventMessage: &pb.MessageWrapper_AccountUpdate{
+		AccountUpdate: &pb.SubscribeUpdateAccountInfo{Pubkey: pubkey, WriteVersion: writeVersion},
+	}}
+}
+
+// describe summarizes the event of a message
+func describe(msg *pb.MessageWrapper) string {
+	switch ev := msg.EventMessage.(type) {
+	case *pb.MessageWrapper_Transaction:
+		return fmt.Sprintf("tx %d", ev.Transaction.Transaction.Signature[0])
+	case *pb.MessageWrapper_Slot:
+		return fmt.Sprintf("slot %d", ev.Slot.Slot)
+	case *pb.MessageWrapper_AccountUpdate:
+		return fmt.Sprintf("account %d", ev.AccountUpdate.Pubkey[0])
+	default:
+		return "unknown"
+	}
+}
+
+func TestStreamExtract(t *testing.T) {
+	wallet := txMessage(1, []byte{1})
+	wallet.GetTransaction().StreamType = pb.StreamType_STREAM_TYPE_WALLET
+	messages := []*pb.MessageWrapper{wallet, slotMessage(7, 0), accountMessage(3, 1)}
+
+	tests := []struct {
+		name string
+		// open subscribes and returns a function receiving the next event
+		open func(ctx context.Context, c *Client) (func() (string, error), error)
+		want []string
+	}{
+		{
+			name: "transactions",
+			open: func(ctx context.Context, c *Client) (func() (string, error), error) {
+				s, err := c.SubscribeToTransactions(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return func() (string, error) {
+					tx, err := s.Recv()
+					if err != nil {
+						return "", err
+					}
+					return fmt.Sprintf("tx %d %v", tx.Signature[0], s.StreamType()), nil
+				}, nil
+			},
+			want: []string{"tx 1 STREAM_TYPE_WALLET"},
+		},
+		{
+			name: "slots",
+			open: func(ctx context.Context, c *Client) (func() (string, error), error) {
+				s, err := c.SubscribeToSlotStatus(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return func() (string, error) {
+					ev, err := s.Recv()
+					if err != nil {
+						return "", err
+					}
+					return fmt.Sprintf("slot %d", ev.Slot), nil
+				}, nil
+			},
+			want: []string{"slot 7"},
+		},
+		{
+			name: "accounts",
+			open: func(ctx context.Context, c *Client) (func() (string, error), error) {
+				s, err := c.SubscribeToAccountUpdates(ctx, []string{testWallet(3)}, nil)
+				if err != nil {
+					return nil, err
+				}
+				return func() (string, error) {
+					ev, err := s.Recv()
+					if err != nil {
+						return "", err
+					}
+					return fmt.Sprintf("account %d", ev.Pubkey[0]), nil
+				}, nil
+			},
+			want: []string{"account 3"},
+		},
+		{
+			name: "thor updates",
+			open: func(ctx context.Context, c *Client) (func() (string, error), error) {
+				s, err := c.SubscribeToThorUpdates(ctx)
+				if err != nil {
+					return nil, err
+				}
+				return func() (string, error) {
+					msg, err := s.Recv()
+					if err != nil {
+						return "", err
+					}
+					return describe(msg), nil
+				}, nil
+			},
+			want: []string{"tx 1", "slot 7", "account 3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, _ := startPublisher(t, "", func(ctx context.Context, _ any, send func(*pb.MessageWrapper) error) error {
+				for _, msg := range messages {
+					if err := send(msg); err != nil {
+						return err
+					}
+				}
+				return idle(ctx)
+			})
+			c, err := NewClient(Config{ServerAddr: addr})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			recv, err := tt.open(ctx, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for range tt.want {
+				ev, err := recv()
+				if err != nil {
+					t.Fatalf("Recv after %q: %v", got, err)
+				}
+				got = append(got, ev)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("received %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStreamAll(t *testing.T) {
+	tests := []struct {
+		name string
+		// end is returned by the server after sending the transactions
+		end      error
+		cancel   bool // keep the stream open and cancel it after the first event
+		want     []byte
+		wantCode codes.Code
+	}{
+		{name: "ends when the server closes", want: []byte{1, 2}},
+		{name: "yields the final error", end: status.Error(codes.PermissionDenied, "denied"), want: []byte{1, 2}, wantCode: codes.PermissionDenied},
+		{name: "ends when cancelled", cancel: true, want: []byte{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, _ := startPublisher(t, "", func(ctx context.Context, _ any, send func(*pb.MessageWrapper) error) error {
+				for _, sig := range tt.want {
+					if err := send(txMessage(1, []byte{sig})); err != nil {
+						return err
+					}
+				}
+				if tt.cancel {
+					return idle(ctx)
+				}
+				return tt.end
+			})
+			c, err := NewClient(Config{ServerAddr: addr, MaxRetries: -1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			s, err := c.SubscribeToTransactions(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []byte
+			var final error
+			for tx, err := range s.All() {
+				if err != nil {
+					final = err
+					break
+				}
+				got = append(got, tx.Signature...)
+				if tt.cancel {
+					cancel()
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("iterated over %v, want %v", got, tt.want)
+			}
+			if status.Code(final) != tt.wantCode {
+				t.Errorf("final error = %v, want code %v", final, tt.wantCode)
+			}
+		})
+	}
+}
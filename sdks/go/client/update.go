@@ -0,0 +1,102 @@
+package thorclient
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+// WalletStream delivers the transactions of a set of wallets that can be
+// changed with Update
+type WalletStream struct {
+	*Stream[*pb.TransactionEvent]
+	client *Client
+
+	mu      sync.Mutex
+	wallets []string
+}
+
+// Update replaces the subscribed wallets without a gap. The new subscription
+// is opened before the old one is closed, and transactions delivered by both
+// during the overlap are returned once.
+func (ws *WalletStream) Update(wallets []string) error {
+	if err := ws.client.registry.validateWallets(wallets); err != nil {
+		return err
+	}
+
+	wallets = slices.Clone(wallets)
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	err := ws.replace(func(ctx context.Context) (*resumableStream, error) {
+		return ws.client.openWallets(ctx, wallets)
+	})
+	if err != nil {
+		return err
+	}
+	ws.wallets = wallets
+	return nil
+}
+
+// Wallets returns the subscribed wallets
+func (ws *WalletStream) Wallets() []string {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return slices.Clone(ws.wallets)
+}
+
+func (ws *WalletStream) update(addresses []string) error {
+	return ws.Update(addresses)
+}
+
+// AccountStream delivers the updates of a set of accounts and owners that can
+// be changed with Update
+type AccountStream struct {
+	*Stream[*pb.SubscribeUpdateAccountInfo]
+	client *Client
+
+	mu       sync.Mutex
+	accounts []string
+	owners   []string
+}
+
+// Update replaces the subscribed accounts and owners without a gap. Updates
+// delivered by both the old and new subscription are returned once, keyed by
+// pubkey and write_version.
+func (as *AccountStream) Update(accounts, owners []string) error {
+	if err := as.client.registry.validateAccounts(accounts, owners); err != nil {
+		return err
+	}
+
+	accounts, owners = slices.Clone(accounts), slices.Clone(owners)
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	err := as.replace(func(ctx context.Context) (*resumableStream, error) {
+		return as.client.openAccounts(ctx, accounts, owners)
+	})
+	if err != nil {
+		return err
+	}
+	as.accounts, as.owners = accounts, owners
+	return nil
+}
+
+// Accounts returns the subscribed accounts
+func (as *AccountStream) Accounts() []string {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return slices.Clone(as.accounts)
+}
+
+// Owners returns the subscribed owners
+func (as *AccountStream) Owners() []string {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+	return slices.Clone(as.owners)
+}
+
+// update replaces the accounts and keeps the current owner filter
+func (as *AccountStream) update(addresses []string) error {
+	return as.Update(addresses, as.Owners())
+}
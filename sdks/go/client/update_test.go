@@ -0,0 +1,202 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+func TestWalletUpdate(t *testing.T) {
+	updated := make(chan struct{})
+	oldClosed := make(chan struct{})
+	var requests walletRequests
+	addr, _ := startPublisher(t, "", func(ctx context.Context, req any, send func(*pb.MessageWrapper) error) error {
+		sendAll := func(sigs ...byte) error {
+			for _, sig := range sigs {
+				if err := send(txMessage(1, []byte{sig})); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if requests.add(req) == 0 {
+			// The old subscription keeps delivering during the overlap
+			if err := sendAll(1, 2); err != nil {
+				return err
+			}
+			<-updated
+			if err := sendAll(3); err != nil {
+				return err
+			}
+			<-ctx.Done()
+			close(oldClosed)
+			return nil
+		}
+		if err := sendAll(2, 3, 4); err != nil {
+			return err
+		}
+		return idle(ctx)
+	})
+	c, err := NewClient(Config{ServerAddr: addr, UpdateOverlap: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	s, err := c.SubscribeToWalletTransactions(context.Background(), []string{testWallet(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var got []byte
+	recv := func(n int) {
+		t.Helper()
+		for range n {
+			tx, err := s.Recv()
+			if err != nil {
+				t.Fatalf("Recv after %v: %v", got, err)
+			}
+			got = append(got, tx.Signature...)
+		}
+	}
+	recv(2)
+	next := []string{testWallet(1), testWallet(2)}
+	if err := s.Update(next); err != nil {
+		t.Fatal(err)
+	}
+	close(updated)
+	if subs := c.Subscriptions(); len(subs) != 2 {
+		t.Errorf("%d subscriptions during the overlap, want 2", len(subs))
+	}
+
+	recv(2)
+	slices.Sort(got)
+	if want := []byte{1, 2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("received %v across the update, want %v", got, want)
+	}
+	extra := make(chan byte, 1)
+	go func() {
+		if tx, err := s.Recv(); err == nil {
+			extra <- tx.Signature[0]
+		}
+	}()
+	select {
+	case <-oldClosed:
+	case <-time.After(time.Second):
+		t.Fatal("old subscription not closed after the overlap")
+	}
+	select {
+	case sig := <-extra:
+		t.Errorf("received transaction %d twice", sig)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	eventually(t, "the old quota to be freed", func() bool { return len(c.Subscriptions()) == 1 })
+	if subs := c.Subscriptions(); !slices.Equal(subs[0].Addresses, next) {
+		t.Errorf("open subscription has wallets %v, want %v", subs[0].Addresses, next)
+	}
+	if !slices.Equal(s.Wallets(), next) {
+		t.Errorf("Wallets() = %v, want %v", s.Wallets(), next)
+	}
+}
+
+func TestWalletUpdateFails(t *testing.T) {
+	sendNext := make(chan byte)
+	addr, _ := startPublisher(t, "", func(ctx context.Context, _ any, send func(*pb.MessageWrapper) error) error {
+		for {
+			select {
+			case sig := <-sendNext:
+				if err := send(txMessage(1, []byte{sig})); err != nil {
+					return err
+				}
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	})
+	c, err := NewClient(Config{ServerAddr: addr, Limits: Limits{Wallets: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	wallets := []string{testWallet(1)}
+	s, err := c.SubscribeToWalletTransactions(context.Background(), wallets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	tests := []struct {
+		name    string
+		wallets []string
+		want    error
+	}{
+		{"no quota for the overlap", []string{testWallet(2)}, ErrWalletSubscriptionLimitReached},
+		{"invalid wallet", []string{"invalid"}, ErrInvalidWalletAddress},
+		{"no wallets", nil, ErrEmptyWalletList},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Update(tt.wallets); !errors.Is(err, tt.want) {
+				t.Fatalf("Update() = %v, want %v", err, tt.want)
+			}
+			if !slices.Equal(s.Wallets(), wallets) {
+				t.Errorf("Wallets() = %v, want %v", s.Wallets(), wallets)
+			}
+			// The old subscription is still delivering
+			sendNext <- byte(i)
+			if tx, err := s.Recv(); err != nil || tx.Signature[0] != byte(i) {
+				t.Errorf("Recv() = %v, %v, want transaction %d", tx, err, i)
+			}
+		})
+	}
+}
+
+func TestAccountUpdateKeepsOwners(t *testing.T) {
+	var mu sync.Mutex
+	var requests []*pb.SubscribeAccountsRequest
+	addr, _ := startPublisher(t, "", func(ctx context.Context, req any, _ func(*pb.MessageWrapper) error) error {
+		mu.Lock()
+		requests = append(requests, req.(*pb.SubscribeAccountsRequest))
+		mu.Unlock()
+		return idle(ctx)
+	})
+	c, err := NewClient(Config{ServerAddr: addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	owners := []string{testWallet(9)}
+	s, err := c.SubscribeToAccountUpdates(context.Background(), []string{testWallet(1)}, owners)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	// The sharded stream updates accounts through update
+	accounts := []string{testWallet(2), testWallet(3)}
+	if err := s.update(accounts); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(s.Accounts(), accounts) || !slices.Equal(s.Owners(), owners) {
+		t.Errorf("accounts %v and owners %v, want %v and %v", s.Accounts(), s.Owners(), accounts, owners)
+	}
+
+	eventually(t, "the new subscription", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(requests) == 2
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	// The server may see the two streams in either order
+	i := slices.IndexFunc(requests, func(req *pb.SubscribeAccountsRequest) bool { return len(req.AccountAddress) == 2 })
+	if req := requests[max(i, 0)]; !slices.Equal(req.AccountAddress, accounts) || !slices.Equal(req.OwnerAddress, owners) {
+		t.Errorf("server got accounts %v and owners %v, want %v and %v", req.AccountAddress, req.OwnerAddress, accounts, owners)
+	}
+}
@@ -0,0 +1,97 @@
+package thorclient
+
+import (
+	"time"
+)
+
+// slotStallTimeout is the default stall timeout of slot streams, which
+// normally deliver an update about every 400ms
+const slotStallTimeout = 5 * time.Second
+
+// Watchdog configures stall detection. A stream is stalled when Recv has
+// waited longer than the stream's timeout without receiving a message; each
+// stall is reported once on the Status channel as StatusStalled.
+type Watchdog struct {
+	// Timeouts overrides the stall timeout per stream type. A negative value
+	// disables detection for that type. By default slot streams stall after
+	// 5s, transaction and thor streams after Config.DefaultTimeout, and wallet
+	// and account streams, which may be quiet for long periods, are not watched.
+	Timeouts map[SubscriptionType]time.Duration
+	// OnStall is called from the watchdog goroutine with each StatusStalled event
+	OnStall func(StatusEvent)
+	// Reconnect re-establishes stalled streams as if they had failed with
+	// ErrStreamStalled
+	Reconnect bool
+}
+
+// stallTimeout returns the stall timeout of streams of kind, or 0 if they are
+// not watched
+func (c *Client) stallTimeout(kind SubscriptionType) time.Duration {
+	if timeout, ok := c.watchdog.Timeouts[kind]; ok && timeout != 0 {
+		return max(timeout, 0)
+	}
+	switch kind {
+	case SubscriptionSlots:
+		return slotStallTimeout
+	case SubscriptionTransactions, SubscriptionThor:
+		return c.defaultTimeout
+	default:
+		return 0
+	}
+}
+
+// watch reports the stream as stalled whenever a Recv call waits longer than
+// timeout, until the stream is closed
+func (rs *resumableStream) watch(timeout time.Duration) {
+	ticker := time.NewTicker(max(timeout/4, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	var reported int64
+	for {
+		select {
+		case <-rs.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		since := rs.waitingSince.Load()
+		if since == 0 || since == reported {
+			continue
+		}
+		if idle := time.Since(time.Unix(0, since)); idle >= timeout {
+			reported = since
+			rs.stall(idle)
+		}
+	}
+}
+
+// stall reports a stall and, if configured, cancels the current attempt so
+// Recv reconnects
+func (rs *resumableStream) stall(idle time.Duration) {
+	c := rs.client
+	ev := StatusEvent{
+		Type:   StatusStalled,
+		Stream: rs.kind,
+		Idle:   idle,
+		Err:    localError(ErrStreamStalled, "%s: no message for %s", rs.kind, idle.Round(time.Millisecond)),
+		Time:   time.Now(),
+	}
+	c.emit(ev)
+	if c.watchdog.OnStall != nil {
+		c.watchdog.OnStall(ev)
+	}
+
+	if c.watchdog.Reconnect {
+		rs.mu.Lock()
+		rs.attemptCancel(ev.Err)
+		rs.mu.Unlock()
+	}
+}
+
+// lastReceived returns when the stream last received a message
+func (rs *resumableStream) lastReceived() time.Time {
+	if nanos := rs.lastMessage.Load(); nanos != 0 {
+		return time.Unix(0, nanos)
+	}
+	return time.Time{}
+}
@@ -0,0 +1,128 @@
+package thorclient
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/thorlabsDev/ThorStreamer/sdks/go/proto"
+)
+
+func TestStallTimeout(t *testing.T) {
+	defaults := &Client{defaultTimeout: 30 * time.Second}
+	custom := &Client{defaultTimeout: 30 * time.Second, watchdog: Watchdog{Timeouts: map[SubscriptionType]time.Duration{
+		SubscriptionSlots:        -1,
+		SubscriptionTransactions: time.Second,
+		SubscriptionWallets:      time.Minute,
+	}}}
+
+	tests := []struct {
+		c    *Client
+		kind SubscriptionType
+		want time.Duration
+	}{
+		{defaults, SubscriptionSlots, slotStallTimeout},
+		{defaults, SubscriptionTransactions, 30 * time.Second},
+		{defaults, SubscriptionThor, 30 * time.Second},
+		{defaults, SubscriptionWallets, 0},
+		{defaults, SubscriptionAccounts, 0},
+		{custom, SubscriptionSlots, 0},
+		{custom, SubscriptionTransactions, time.Second},
+		{custom, SubscriptionThor, 30 * time.Second},
+		{custom, SubscriptionWallets, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := tt.c.stallTimeout(tt.kind); got != tt.want {
+			t.Errorf("stallTimeout(%s) = %v, want %v", tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestWatchdog(t *testing.T) {
+	tests := []struct {
+		name       string
+		reconnect  bool
+		wantStatus []StatusEventType
+		wantCalls  int32
+	}{
+		{name: "reports a stall once", wantStatus: []StatusEventType{StatusStalled}, wantCalls: 1},
+		{name: "reconnects", reconnect: true, wantStatus: []StatusEventType{StatusStalled, StatusReconnecting, StatusReconnected}, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			addr, _ := startPublisher(t, "", func(ctx context.Context, _ any, send func(*pb.MessageWrapper) error) error {
+				// Each stream delivers one slot and then goes quiet
+				if err := send(slotMessage(uint64(calls.Add(1)), 0)); err != nil {
+					return err
+				}
+				return idle(ctx)
+			})
+
+			var mu sync.Mutex
+			var stalls []StatusEvent
+			c, err := NewClient(Config{ServerAddr: addr, InitialBackoff: time.Millisecond, Watchdog: Watchdog{
+				Timeouts: map[SubscriptionType]time.Duration{SubscriptionSlots: 50 * time.Millisecond},
+				OnStall: func(ev StatusEvent) {
+					mu.Lock()
+					stalls = append(stalls, ev)
+					mu.Unlock()
+				},
+				Reconnect: tt.reconnect,
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+			s, err := c.SubscribeToSlotStatus(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+
+			if _, err := s.Recv(); err != nil {
+				t.Fatal(err)
+			}
+			received := make(chan uint64, 1)
+			go func() {
+				if ev, err := s.Recv(); err == nil {
+					received <- ev.Slot
+				}
+			}()
+			if tt.reconnect {
+				select {
+				case slot := <-received:
+					if slot != 2 {
+						t.Errorf("received slot %d after the stall, want 2 from the new stream", slot)
+					}
+				case <-time.After(time.Second):
+					t.Fatal("stalled stream not reconnected")
+				}
+			} else {
+				time.Sleep(200 * time.Millisecond)
+			}
+
+			if n := calls.Load(); n != tt.wantCalls {
+				t.Errorf("stream opened %d times, want %d", n, tt.wantCalls)
+			}
+			if got := statusTypes(c); !slices.Equal(got, tt.wantStatus) {
+				t.Errorf("status events %v, want %v", got, tt.wantStatus)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(stalls) != 1 {
+				t.Fatalf("OnStall called %d times, want 1", len(stalls))
+			}
+			if ev := stalls[0]; ev.Stream != SubscriptionSlots || ev.Idle < 50*time.Millisecond || !errors.Is(ev.Err, ErrStreamStalled) {
+				t.Errorf("stall event %+v, want a slot stream idle for 50ms with ErrStreamStalled", ev)
+			}
+			if s.LastMessage().IsZero() {
+				t.Error("LastMessage() is zero after receiving")
+			}
+		})
+	}
+}
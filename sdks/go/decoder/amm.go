@@ -0,0 +1,413 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Program IDs of the AMMs with built-in decoders
+const (
+	RaydiumAMMV4ProgramID  = "675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8"
+	RaydiumCLMMProgramID   = "CAMMCzo5YL8w4VFF8KVHrK22GGUsp5VTaW7grrKgrWqK"
+	RaydiumCPMMProgramID   = "CPMMoo8L3F4NbTegBCKVNunggL7H1ZpdTHKxQB5qKP1C"
+	OrcaWhirlpoolProgramID = "whirLbMiicVdio4qvUfM5KAg6Ct8VwpYzGff3uctyCc"
+	MeteoraDLMMProgramID   = "LBUZKhRxPF3XUpBCjp4YzTKgLccjZhTSDM9YuVaPwxo"
+	PumpFunProgramID       = "6EF8rrecthR5Dkzon8Nwu78hRvfCKubJ14M5uBEwF6P"
+)
+
+// raydiumAMMV4Size is the size of a Raydium AMM v4 pool, which has no
+// discriminator
+const raydiumAMMV4Size = 752
+
+// ErrDiscriminator means account data does not start with the discriminator
+// of the layout it was decoded as
+var ErrDiscriminator = errors.New("discriminator mismatch")
+
+// RaydiumAMMV4Pool is a Raydium AMM v4 (constant product) pool
+type RaydiumAMMV4Pool struct {
+	Status        uint64
+	BaseDecimals  uint8
+	QuoteDecimals uint8
+	// Fees are fractions numerator/denominator of the swapped amount
+	TradeFeeNumerator   uint64
+	TradeFeeDenominator uint64
+	SwapFeeNumerator    uint64
+	SwapFeeDenominator  uint64
+	// NeedTakePnlBase and NeedTakePnlQuote are held in the vaults but owed
+	// to the protocol
+	NeedTakePnlBase  uint64
+	NeedTakePnlQuote uint64
+	PoolOpenTime     uint64
+	BaseVault        string
+	QuoteVault       string
+	BaseMint         string
+	QuoteMint        string
+	LPMint           string
+	OpenOrders       string
+	Market           string
+	MarketProgram    string
+	TargetOrders     string
+	AMMOwner         string
+	LPAmount         uint64
+}
+
+// Reserves returns the pool's reserves given its vault balances
+func (p *RaydiumAMMV4Pool) Reserves(baseVault, quoteVault uint64) (base, quote uint64) {
+	return sub(baseVault, p.NeedTakePnlBase), sub(quoteVault, p.NeedTakePnlQuote)
+}
+
+// DecodeRaydiumAMMV4Pool decodes a Raydium AMM v4 pool
+func DecodeRaydiumAMMV4Pool(data []byte) (*RaydiumAMMV4Pool, error) {
+	if len(data) != raydiumAMMV4Size {
+		return nil, fmt.Errorf("raydium amm v4 pool: %w: %d bytes", ErrUnknownLayout, len(data))
+	}
+	r := newReader(data)
+	p := &RaydiumAMMV4Pool{Status: r.u64()}
+	r.skip(3 * 8) // nonce, max_order, depth
+	p.BaseDecimals = uint8(r.u64())
+	p.QuoteDecimals = uint8(r.u64())
+	r.skip(10 * 8) // state .. sys_decimal_value
+	r.skip(2 * 8)  // min_separate
+	p.TradeFeeNumerator, p.TradeFeeDenominator = r.u64(), r.u64()
+	r.skip(2 * 8) // pnl
+	p.SwapFeeNumerator, p.SwapFeeDenominator = r.u64(), r.u64()
+	p.NeedTakePnlBase, p.NeedTakePnlQuote = r.u64(), r.u64()
+	r.skip(2 * 8) // total_pnl
+	p.PoolOpenTime = r.u64()
+	r.skip(3*8 + 2*16 + 8 + 2*16 + 8) // punish amounts .. swap statistics
+	p.BaseVault, p.QuoteVault = r.pubkey(), r.pubkey()
+	p.BaseMint, p.QuoteMint = r.pubkey(), r.pubkey()
+	p.LPMint, p.OpenOrders = r.pubkey(), r.pubkey()
+	p.Market, p.MarketProgram = r.pubkey(), r.pubkey()
+	p.TargetOrders = r.pubkey()
+	r.skip(2 * 32) // withdraw_queue, token_temp_lp
+	p.AMMOwner = r.pubkey()
+	p.LPAmount = r.u64()
+	if r.err != nil {
+		return nil, fmt.Errorf("raydium amm v4 pool: %w", r.err)
+	}
+	return p, nil
+}
+
+// RaydiumCLMMPool is a Raydium concentrated liquidity pool
+type RaydiumCLMMPool struct {
+	AMMConfig      string
+	Owner          string
+	TokenMint0     string
+	TokenMint1     string
+	TokenVault0    string
+	TokenVault1    string
+	ObservationKey string
+	MintDecimals0  uint8
+	MintDecimals1  uint8
+	TickSpacing    uint16
+	Liquidity      *big.Int
+	// SqrtPriceX64 is the square root of the price of token 0 in token 1 as
+	// a Q64.64 fixed-point number
+	SqrtPriceX64 *big.Int
+	TickCurrent  int32
+	Status       uint8
+}
+
+// Price returns the price of token 0 in token 1
+func (p *RaydiumCLMMPool) Price() float64 {
+	return SqrtPriceX64ToPrice(p.SqrtPriceX64, p.MintDecimals0, p.MintDecimals1)
+}
+
+// DecodeRaydiumCLMMPool decodes a Raydium CLMM PoolState
+func DecodeRaydiumCLMMPool(data []byte) (*RaydiumCLMMPool, error) {
+	r, err := anchorReader(data, "PoolState")
+	if err != nil {
+		return nil, fmt.Errorf("raydium clmm pool: %w", err)
+	}
+	r.skip(1) // bump
+	p := &RaydiumCLMMPool{
+		AMMConfig:      r.pubkey(),
+		Owner:          r.pubkey(),
+		TokenMint0:     r.pubkey(),
+		TokenMint1:     r.pubkey(),
+		TokenVault0:    r.pubkey(),
+		TokenVault1:    r.pubkey(),
+		ObservationKey: r.pubkey(),
+		MintDecimals0:  r.u8(),
+		MintDecimals1:  r.u8(),
+		TickSpacing:    r.u16(),
+		Liquidity:      r.u128(),
+		SqrtPriceX64:   r.u128(),
+		TickCurrent:    r.i32(),
+	}
+	r.skip(2*2 + 2*16 + 2*8 + 4*16) // padding, fee growth, protocol fees, swap totals
+	p.Status = r.u8()
+	if r.err != nil {
+		return nil, fmt.Errorf("raydium clmm pool: %w", r.err)
+	}
+	return p, nil
+}
+
+// RaydiumCPMMPool is a Raydium CPMM (constant product) pool
+type RaydiumCPMMPool struct {
+	AMMConfig      string
+	PoolCreator    string
+	Token0Vault    string
+	Token1Vault    string
+	LPMint         string
+	Token0Mint     string
+	Token1Mint     string
+	Token0Program  string
+	Token1Program  string
+	ObservationKey string
+	Status         uint8
+	LPMintDecimals uint8
+	Mint0Decimals  uint8
+	Mint1Decimals  uint8
+	LPSupply       uint64
+	// Fees held in the vaults but owed to the protocol and fund
+	ProtocolFeesToken0 uint64
+	ProtocolFeesToken1 uint64
+	FundFeesToken0     uint64
+	FundFeesToken1     uint64
+	OpenTime           uint64
+	RecentEpoch        uint64
+}
+
+// Reserves returns the pool's reserves given its vault balances
+func (p *RaydiumCPMMPool) Reserves(vault0, vault1 uint64) (reserve0, reserve1 uint64) {
+	return sub(vault0, p.ProtocolFeesToken0+p.FundFeesToken0), sub(vault1, p.ProtocolFeesToken1+p.FundFeesToken1)
+}
+
+// DecodeRaydiumCPMMPool decodes a Raydium CPMM PoolState
+func DecodeRaydiumCPMMPool(data []byte) (*RaydiumCPMMPool, error) {
+	r, err := anchorReader(data, "PoolState")
+	if err != nil {
+		return nil, fmt.Errorf("raydium cpmm pool: %w", err)
+	}
+	p := &RaydiumCPMMPool{
+		AMMConfig:      r.pubkey(),
+		PoolCreator:    r.pubkey(),
+		Token0Vault:    r.pubkey(),
+		Token1Vault:    r.pubkey(),
+		LPMint:         r.pubkey(),
+		Token0Mint:     r.pubkey(),
+		Token1Mint:     r.pubkey(),
+		Token0Program:  r.pubkey(),
+		Token1Program:  r.pubkey(),
+		ObservationKey: r.pubkey(),
+	}
+	r.skip(1) // auth_bump
+	p.Status = r.u8()
+	p.LPMintDecimals, p.Mint0Decimals, p.Mint1Decimals = r.u8(), r.u8(), r.u8()
+	p.LPSupply = r.u64()
+	p.ProtocolFeesToken0, p.ProtocolFeesToken1 = r.u64(), r.u64()
+	p.FundFeesToken0, p.FundFeesToken1 = r.u64(), r.u64()
+	p.OpenTime, p.RecentEpoch = r.u64(), r.u64()
+	if r.err != nil {
+		return nil, fmt.Errorf("raydium cpmm pool: %w", r.err)
+	}
+	return p, nil
+}
+
+// Whirlpool is an Orca Whirlpool concentrated liquidity pool
+type Whirlpool struct {
+	WhirlpoolsConfig string
+	TickSpacing      uint16
+	// FeeRate is in hundredths of a basis point, ProtocolFeeRate in basis
+	// points of the fee
+	FeeRate         uint16
+	ProtocolFeeRate uint16
+	Liquidity       *big.Int
+	// SqrtPrice is the square root of the price of token A in token B as a
+	// Q64.64 fixed-point number
+	SqrtPrice                  *big.Int
+	TickCurrentIndex           int32
+	ProtocolFeeOwedA           uint64
+	ProtocolFeeOwedB           uint64
+	TokenMintA                 string
+	TokenVaultA                string
+	FeeGrowthGlobalA           *big.Int
+	TokenMintB                 string
+	TokenVaultB                string
+	FeeGrowthGlobalB           *big.Int
+	RewardLastUpdatedTimestamp uint64
+}
+
+// Price returns the price of token A in token B given the mints' decimals
+func (w *Whirlpool) Price(decimalsA, decimalsB uint8) float64 {
+	return SqrtPriceX64ToPrice(w.SqrtPrice, decimalsA, decimalsB)
+}
+
+// DecodeWhirlpool decodes an Orca Whirlpool
+func DecodeWhirlpool(data []byte) (*Whirlpool, error) {
+	r, err := anchorReader(data, "Whirlpool")
+	if err != nil {
+		return nil, fmt.Errorf("whirlpool: %w", err)
+	}
+	w := &Whirlpool{WhirlpoolsConfig: r.pubkey()}
+	r.skip(1) // bump
+	w.TickSpacing = r.u16()
+	r.skip(2) // tick_spacing_seed
+	w.FeeRate, w.ProtocolFeeRate = r.u16(), r.u16()
+	w.Liquidity, w.SqrtPrice = r.u128(), r.u128()
+	w.TickCurrentIndex = r.i32()
+	w.ProtocolFeeOwedA, w.ProtocolFeeOwedB = r.u64(), r.u64()
+	w.TokenMintA, w.TokenVaultA, w.FeeGrowthGlobalA = r.pubkey(), r.pubkey(), r.u128()
+	w.TokenMintB, w.TokenVaultB, w.FeeGrowthGlobalB = r.pubkey(), r.pubkey(), r.u128()
+	w.RewardLastUpdatedTimestamp = r.u64()
+	if r.err != nil {
+		return nil, fmt.Errorf("whirlpool: %w", r.err)
+	}
+	return w, nil
+}
+
+// MeteoraDLMMPair is a Meteora DLMM liquidity book pair
+type MeteoraDLMMPair struct {
+	BaseFactor               uint16
+	FilterPeriod             uint16
+	DecayPeriod              uint16
+	ReductionFactor          uint16
+	VariableFeeControl       uint32
+	MaxVolatilityAccumulator uint32
+	MinBinID                 int32
+	MaxBinID                 int32
+	ProtocolShare            uint16
+	VolatilityAccumulator    uint32
+	VolatilityReference      uint32
+	IndexReference           int32
+	LastUpdateTimestamp      int64
+	PairType                 uint8
+	// ActiveID is the bin holding the current price
+	ActiveID       int32
+	BinStep        uint16
+	Status         uint8
+	ActivationType uint8
+	TokenXMint     string
+	TokenYMint     string
+	ReserveX       string
+	ReserveY       string
+	ProtocolFeeX   uint64
+	ProtocolFeeY   uint64
+}
+
+// Price returns the price of token X in token Y at the active bin given the
+// mints' decimals
+func (p *MeteoraDLMMPair) Price(decimalsX, decimalsY uint8) float64 {
+	return math.Pow(1+float64(p.BinStep)/10_000, float64(p.ActiveID)) * math.Pow10(int(decimalsX)-int(decimalsY))
+}
+
+// DecodeMeteoraDLMMPair decodes a Meteora DLMM LbPair
+func DecodeMeteoraDLMMPair(data []byte) (*MeteoraDLMMPair, error) {
+	r, err := anchorReader(data, "LbPair")
+	if err != nil {
+		return nil, fmt.Errorf("meteora dlmm pair: %w", err)
+	}
+	p := &MeteoraDLMMPair{
+		BaseFactor:               r.u16(),
+		FilterPeriod:             r.u16(),
+		DecayPeriod:              r.u16(),
+		ReductionFactor:          r.u16(),
+		VariableFeeControl:       r.u32(),
+		MaxVolatilityAccumulator: r.u32(),
+		MinBinID:                 r.i32(),
+		MaxBinID:                 r.i32(),
+		ProtocolShare:            r.u16(),
+	}
+	r.skip(1 + 5) // base_fee_power_factor, padding
+	p.VolatilityAccumulator, p.VolatilityReference = r.u32(), r.u32()
+	p.IndexReference = r.i32()
+	r.skip(4)
+	p.LastUpdateTimestamp = r.i64()
+	r.skip(8 + 1 + 2) // padding, bump_seed, bin_step_seed
+	p.PairType = r.u8()
+	p.ActiveID = r.i32()
+	p.BinStep = r.u16()
+	p.Status = r.u8()
+	r.skip(1 + 2) // require_base_factor_seed, base_factor_seed
+	p.ActivationType = r.u8()
+	r.skip(1) // creator_pool_on_off_control
+	p.TokenXMint, p.TokenYMint = r.pubkey(), r.pubkey()
+	p.ReserveX, p.ReserveY = r.pubkey(), r.pubkey()
+	p.ProtocolFeeX, p.ProtocolFeeY = r.u64(), r.u64()
+	if r.err != nil {
+		return nil, fmt.Errorf("meteora dlmm pair: %w", r.err)
+	}
+	return p, nil
+}
+
+// PumpBondingCurve is the bonding curve of a Pump.fun token before it
+// migrates. Reserves are in lamports and raw token units.
+type PumpBondingCurve struct {
+	VirtualTokenReserves uint64
+	VirtualSolReserves   uint64
+	RealTokenReserves    uint64
+	RealSolReserves      uint64
+	TokenTotalSupply     uint64
+	// Complete is set once the curve has sold out and migrates
+	Complete bool
+	// Creator is "" on curves created before creators were recorded
+	Creator string
+}
+
+// pumpTokenDecimals is the decimals of every Pump.fun token
+const pumpTokenDecimals = 6
+
+// Price returns the price of one whole token in SOL
+func (c *PumpBondingCurve) Price() float64 {
+	if c.VirtualTokenReserves == 0 {
+		return 0
+	}
+	return UIAmount(c.VirtualSolReserves, 9) / UIAmount(c.VirtualTokenReserves, pumpTokenDecimals)
+}
+
+// DecodePumpBondingCurve decodes a Pump.fun BondingCurve
+func DecodePumpBondingCurve(data []byte) (*PumpBondingCurve, error) {
+	r, err := anchorReader(data, "BondingCurve")
+	if err != nil {
+		return nil, fmt.Errorf("pump bonding curve: %w", err)
+	}
+	c := &PumpBondingCurve{
+		VirtualTokenReserves: r.u64(),
+		VirtualSolReserves:   r.u64(),
+		RealTokenReserves:    r.u64(),
+		RealSolReserves:      r.u64(),
+		TokenTotalSupply:     r.u64(),
+		Complete:             r.bool(),
+	}
+	if r.remaining() >= 32 {
+		c.Creator = r.optionalPubkey()
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("pump bonding curve: %w", r.err)
+	}
+	return c, nil
+}
+
+// SqrtPriceX64ToPrice converts a Q64.64 square root price to the price of
+// the first token in the second, adjusted for their decimals
+func SqrtPriceX64ToPrice(sqrtPrice *big.Int, decimals0, decimals1 uint8) float64 {
+	if sqrtPrice == nil {
+		return 0
+	}
+	sqrt, _ := new(big.Float).SetMantExp(new(big.Float).SetInt(sqrtPrice), -64).Float64()
+	return sqrt * sqrt * math.Pow10(int(decimals0)-int(decimals1))
+}
+
+// anchorReader checks the Anchor discriminator of the named account type and
+// returns a reader positioned after it
+func anchorReader(data []byte, name string) (*reader, error) {
+	disc := AnchorDiscriminator(name)
+	if len(data) < DiscriminatorSize || [DiscriminatorSize]byte(data) != disc {
+		return nil, fmt.Errorf("%w: not a %s account", ErrDiscriminator, name)
+	}
+	r := newReader(data)
+	r.skip(DiscriminatorSize)
+	return r, nil
+}
+
+// sub returns a-b, or 0 if b exceeds a
+func sub(a, b uint64) uint64 {
+	if b > a {
+		return 0
+	}
+	return a - b
+}
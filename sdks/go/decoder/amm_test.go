@@ -0,0 +1,250 @@
+package decoder
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+// put writes b into data at offset
+func put(data []byte, offset int, b []byte) {
+	copy(data[offset:], b)
+}
+
+func TestAMMLayouts(t *testing.T) {
+	// Each field is written at its offset in the on-chain layout, so a
+	// decoder that skips too much or too little reads the wrong value
+	type field struct {
+		offset int
+		data   []byte
+		name   string
+		want   any
+	}
+	u128 := func(v uint64) []byte { return concat(le64(v), le64(0)) }
+
+	tests := []struct {
+		name          string
+		program       string
+		discriminator string // Anchor account name, "" for none
+		size          int
+		fields        []field
+	}{
+		{
+			name:    "Raydium AMM v4",
+			program: RaydiumAMMV4ProgramID,
+			size:    752,
+			fields: []field{
+				{0, le64(6), "Status", uint64(6)},
+				{32, le64(9), "BaseDecimals", uint8(9)},
+				{40, le64(6), "QuoteDecimals", uint8(6)},
+				{144, le64(25), "TradeFeeNumerator", uint64(25)},
+				{152, le64(10_000), "TradeFeeDenominator", uint64(10_000)},
+				{176, le64(26), "SwapFeeNumerator", uint64(26)},
+				{184, le64(10_001), "SwapFeeDenominator", uint64(10_001)},
+				{192, le64(5), "NeedTakePnlBase", uint64(5)},
+				{200, le64(4), "NeedTakePnlQuote", uint64(4)},
+				{224, le64(1_700_000_000), "PoolOpenTime", uint64(1_700_000_000)},
+				{336, testKey(1), "BaseVault", testAddress(1)},
+				{368, testKey(2), "QuoteVault", testAddress(2)},
+				{400, testKey(3), "BaseMint", testAddress(3)},
+				{432, testKey(4), "QuoteMint", testAddress(4)},
+				{464, testKey(5), "LPMint", testAddress(5)},
+				{496, testKey(6), "OpenOrders", testAddress(6)},
+				{528, testKey(7), "Market", testAddress(7)},
+				{560, testKey(8), "MarketProgram", testAddress(8)},
+				{592, testKey(9), "TargetOrders", testAddress(9)},
+				{688, testKey(10), "AMMOwner", testAddress(10)},
+				{720, le64(777), "LPAmount", uint64(777)},
+			},
+		},
+		{
+			name:          "Raydium CLMM",
+			program:       RaydiumCLMMProgramID,
+			discriminator: "PoolState",
+			size:          1544,
+			fields: []field{
+				{9, testKey(1), "AMMConfig", testAddress(1)},
+				{41, testKey(2), "Owner", testAddress(2)},
+				{73, testKey(3), "TokenMint0", testAddress(3)},
+				{105, testKey(4), "TokenMint1", testAddress(4)},
+				{137, testKey(5), "TokenVault0", testAddress(5)},
+				{169, testKey(6), "TokenVault1", testAddress(6)},
+				{201, testKey(7), "ObservationKey", testAddress(7)},
+				{233, []byte{9}, "MintDecimals0", uint8(9)},
+				{234, []byte{6}, "MintDecimals1", uint8(6)},
+				{235, le16(60), "TickSpacing", uint16(60)},
+				{237, u128(1000), "Liquidity", big.NewInt(1000)},
+				{253, u128(123), "SqrtPriceX64", big.NewInt(123)},
+				{269, le32(math.MaxUint32), "TickCurrent", int32(-1)},
+				{389, []byte{4}, "Status", uint8(4)},
+			},
+		},
+		{
+			name:          "Raydium CPMM",
+			program:       RaydiumCPMMProgramID,
+			discriminator: "PoolState",
+			size:          637,
+			fields: []field{
+				{8, testKey(1), "AMMConfig", testAddress(1)},
+				{40, testKey(2), "PoolCreator", testAddress(2)},
+				{72, testKey(3), "Token0Vault", testAddress(3)},
+				{104, testKey(4), "Token1Vault", testAddress(4)},
+				{136, testKey(5), "LPMint", testAddress(5)},
+				{168, testKey(6), "Token0Mint", testAddress(6)},
+				{200, testKey(7), "Token1Mint", testAddress(7)},
+				{232, testKey(8), "Token0Program", testAddress(8)},
+				{264, testKey(9), "Token1Program", testAddress(9)},
+				{296, testKey(10), "ObservationKey", testAddress(10)},
+				{329, []byte{2}, "Status", uint8(2)},
+				{330, []byte{9}, "LPMintDecimals", uint8(9)},
+				{331, []byte{6}, "Mint0Decimals", uint8(6)},
+				{332, []byte{8}, "Mint1Decimals", uint8(8)},
+				{333, le64(1000), "LPSupply", uint64(1000)},
+				{341, le64(1), "ProtocolFeesToken0", uint64(1)},
+				{349, le64(2), "ProtocolFeesToken1", uint64(2)},
+				{357, le64(3), "FundFeesToken0", uint64(3)},
+				{365, le64(4), "FundFeesToken1", uint64(4)},
+				{373, le64(1_700_000_000), "OpenTime", uint64(1_700_000_000)},
+				{381, le64(700), "RecentEpoch", uint64(700)},
+			},
+		},
+		{
+			name:          "Orca Whirlpool",
+			program:       OrcaWhirlpoolProgramID,
+			discriminator: "Whirlpool",
+			size:          653,
+			fields: []field{
+				{8, testKey(1), "WhirlpoolsConfig", testAddress(1)},
+				{41, le16(64), "TickSpacing", uint16(64)},
+				{45, le16(3000), "FeeRate", uint16(3000)},
+				{47, le16(1300), "ProtocolFeeRate", uint16(1300)},
+				{49, u128(5000), "Liquidity", big.NewInt(5000)},
+				{65, u128(77), "SqrtPrice", big.NewInt(77)},
+				{81, le32(math.MaxUint32 - 1), "TickCurrentIndex", int32(-2)},
+				{85, le64(11), "ProtocolFeeOwedA", uint64(11)},
+				{93, le64(12), "ProtocolFeeOwedB", uint64(12)},
+				{101, testKey(2), "TokenMintA", testAddress(2)},
+				{133, testKey(3), "TokenVaultA", testAddress(3)},
+				{181, testKey(4), "TokenMintB", testAddress(4)},
+				{213, testKey(5), "TokenVaultB", testAddress(5)},
+				{261, le64(1_700_000_000), "RewardLastUpdatedTimestamp", uint64(1_700_000_000)},
+			},
+		},
+		{
+			name:          "Meteora DLMM",
+			program:       MeteoraDLMMProgramID,
+			discriminator: "LbPair",
+			size:          904,
+			fields: []field{
+				{8, le16(10_000), "BaseFactor", uint16(10_000)},
+				{32, le16(500), "ProtocolShare", uint16(500)},
+				{40, le32(7), "VolatilityAccumulator", uint32(7)},
+				{56, le64(1_700_000_000), "LastUpdateTimestamp", int64(1_700_000_000)},
+				{75, []byte{1}, "PairType", uint8(1)},
+				{76, le32(math.MaxUint32 - 99), "ActiveID", int32(-100)},
+				{80, le16(25), "BinStep", uint16(25)},
+				{82, []byte{1}, "Status", uint8(1)},
+				{86, []byte{1}, "ActivationType", uint8(1)},
+				{88, testKey(1), "TokenXMint", testAddress(1)},
+				{120, testKey(2), "TokenYMint", testAddress(2)},
+				{152, testKey(3), "ReserveX", testAddress(3)},
+				{184, testKey(4), "ReserveY", testAddress(4)},
+				{216, le64(8), "ProtocolFeeX", uint64(8)},
+				{224, le64(9), "ProtocolFeeY", uint64(9)},
+			},
+		},
+		{
+			name:          "Pump.fun bonding curve",
+			program:       PumpFunProgramID,
+			discriminator: "BondingCurve",
+			size:          81,
+			fields: []field{
+				{8, le64(1_073_000_000_000_000), "VirtualTokenReserves", uint64(1_073_000_000_000_000)},
+				{16, le64(30_000_000_000), "VirtualSolReserves", uint64(30_000_000_000)},
+				{24, le64(3), "RealTokenReserves", uint64(3)},
+				{32, le64(4), "RealSolReserves", uint64(4)},
+				{40, le64(1_000_000_000_000_000), "TokenTotalSupply", uint64(1_000_000_000_000_000)},
+				{48, []byte{1}, "Complete", true},
+				{49, testKey(5), "Creator", testAddress(5)},
+			},
+		},
+	}
+	registry := DefaultRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := make([]byte, tt.size)
+			if tt.discriminator != "" {
+				disc := AnchorDiscriminator(tt.discriminator)
+				put(data, 0, disc[:])
+			}
+			for _, f := range tt.fields {
+				put(data, f.offset, f.data)
+			}
+
+			v, err := registry.Decode(tt.program, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pool := reflect.ValueOf(v).Elem()
+			for _, f := range tt.fields {
+				got := pool.FieldByName(f.name).Interface()
+				if want, ok := f.want.(*big.Int); ok {
+					if got.(*big.Int).Cmp(want) != 0 {
+						t.Errorf("%s = %v, want %v", f.name, got, want)
+					}
+				} else if got != f.want {
+					t.Errorf("%s = %v, want %v", f.name, got, f.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAMMErrors(t *testing.T) {
+	whirlpool := AnchorDiscriminator("Whirlpool")
+	tests := []struct {
+		name   string
+		decode func() error
+		err    error
+	}{
+		{"wrong discriminator", func() error {
+			_, err := DecodePumpBondingCurve(concat(whirlpool[:], make([]byte, 73)))
+			return err
+		}, ErrDiscriminator},
+		{"truncated", func() error {
+			_, err := DecodeWhirlpool(concat(whirlpool[:], make([]byte, 100)))
+			return err
+		}, ErrShortData},
+		{"AMM v4 size", func() error {
+			_, err := DecodeRaydiumAMMV4Pool(make([]byte, 751))
+			return err
+		}, ErrUnknownLayout},
+	}
+	for _, tt := range tests {
+		if err := tt.decode(); !errors.Is(err, tt.err) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestAMMPrices(t *testing.T) {
+	one := new(big.Int).Lsh(big.NewInt(1), 64)
+	tests := []struct {
+		name  string
+		price float64
+		want  float64
+	}{
+		{"sqrt price 1", SqrtPriceX64ToPrice(one, 6, 6), 1},
+		{"sqrt price 2 adjusted for decimals", SqrtPriceX64ToPrice(new(big.Int).Lsh(one, 1), 9, 6), 4000},
+		{"DLMM active bin", (&MeteoraDLMMPair{ActiveID: 100, BinStep: 25}).Price(6, 6), math.Pow(1.0025, 100)},
+		{"Pump.fun curve", (&PumpBondingCurve{VirtualTokenReserves: 1_000_000_000_000, VirtualSolReserves: 30_000_000_000}).Price(), 0.00003},
+		{"empty Pump.fun curve", (&PumpBondingCurve{}).Price(), 0},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.price-tt.want) > 1e-9*math.Max(1, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.price, tt.want)
+		}
+	}
+}
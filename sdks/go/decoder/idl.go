@@ -0,0 +1,631 @@
+package decoder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxTypeDepth bounds the nesting of decoded IDL types, which may be recursive
+const maxTypeDepth = 64
+
+// maxZeroSizedItems bounds how many more items a list may have than bytes
+// left to decode, which only items of zero-sized types can fit
+const maxZeroSizedItems = 1024
+
+var (
+	// ErrUnknownInstruction means instruction data matches no instruction
+	// of the program's IDL
+	ErrUnknownInstruction = errors.New("unknown instruction")
+	// ErrUnsupportedType means an IDL uses a type the decoder cannot read,
+	// such as generics
+	ErrUnsupportedType = errors.New("unsupported IDL type")
+)
+
+// IDL is an Anchor IDL, in the format of Anchor 0.30 and later or the
+// legacy format of earlier versions
+type IDL struct {
+	// Address is the program ID. Legacy IDLs keep it in their metadata; set
+	// it before registering an IDL that has none.
+	Address      string           `json:"address"`
+	Name         string           `json:"name"`
+	Instructions []IDLInstruction `json:"instructions"`
+	Accounts     []IDLAccount     `json:"accounts"`
+	Types        []IDLTypeDef     `json:"types"`
+
+	types map[string]*IDLTypeDef
+}
+
+// IDLInstruction is an instruction of an IDL
+type IDLInstruction struct {
+	Name          string              `json:"name"`
+	Discriminator []byte              `json:"-"`
+	Accounts      []IDLInstructionAcc `json:"accounts"`
+	Args          []IDLField          `json:"args"`
+}
+
+// IDLInstructionAcc is an account of an instruction, or a group of them
+type IDLInstructionAcc struct {
+	Name     string              `json:"name"`
+	Writable bool                `json:"writable"`
+	Signer   bool                `json:"signer"`
+	Optional bool                `json:"optional"`
+	Accounts []IDLInstructionAcc `json:"accounts"`
+}
+
+// IDLAccount is an account type of an IDL
+type IDLAccount struct {
+	Name          string `json:"name"`
+	Discriminator []byte `json:"-"`
+	// Type is set by legacy IDLs; newer ones describe accounts in Types
+	Type *IDLTypeDefTy `json:"type"`
+}
+
+// IDLTypeDef is a named type of an IDL
+type IDLTypeDef struct {
+	Name string       `json:"name"`
+	Type IDLTypeDefTy `json:"type"`
+}
+
+// IDLTypeDefTy is the definition of a struct, enum or alias
+type IDLTypeDefTy struct {
+	Kind     string       `json:"kind"`
+	Fields   []IDLField   `json:"fields"`
+	Variants []IDLVariant `json:"variants"`
+	Alias    *IDLType     `json:"alias"`
+}
+
+// IDLVariant is an enum variant with optional named or tuple fields
+type IDLVariant struct {
+	Name   string     `json:"name"`
+	Fields []IDLField `json:"fields"`
+}
+
+// IDLField is a named field, or an unnamed tuple field
+type IDLField struct {
+	Name string
+	Type IDLType
+}
+
+// IDLType is a field type: a primitive, a container of another type, or a
+// reference to a defined type
+type IDLType struct {
+	Primitive string
+	Option    *IDLType
+	COption   *IDLType
+	Vec       *IDLType
+	Array     *IDLType
+	ArrayLen  int
+	Defined   string
+	// Unsupported describes a type that cannot be decoded, such as generics
+	Unsupported string
+}
+
+// DecodedInstruction is an instruction decoded with an IDL
+type DecodedInstruction struct {
+	Program   string         `json:"program"`
+	ProgramID string         `json:"program_id"`
+	Name      string         `json:"name"`
+	Args      map[string]any `json:"args"`
+	Accounts  []NamedAccount `json:"accounts"`
+}
+
+// NamedAccount is an instruction account with its IDL name. Accounts past
+// those the IDL lists are named "remaining".
+type NamedAccount struct {
+	Name     string `json:"name"`
+	Pubkey   string `json:"pubkey"`
+	Writable bool   `json:"writable,omitempty"`
+	Signer   bool   `json:"signer,omitempty"`
+}
+
+// DecodedAccount is account data decoded with an IDL
+type DecodedAccount struct {
+	Program string         `json:"program"`
+	Name    string         `json:"name"`
+	Fields  map[string]any `json:"fields"`
+}
+
+// LoadIDL reads an Anchor IDL JSON file
+func LoadIDL(path string) (*IDL, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	idl, err := ParseIDL(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return idl, nil
+}
+
+// ParseIDL parses an Anchor IDL, computing the discriminators legacy IDLs
+// leave out
+func ParseIDL(data []byte) (*IDL, error) {
+	var raw struct {
+		IDL
+		Metadata struct {
+			Name    string `json:"name"`
+			Address string `json:"address"`
+		} `json:"metadata"`
+		Instructions []struct {
+			IDLInstruction
+			Discriminator []byte `json:"discriminator"`
+		} `json:"instructions"`
+		Accounts []struct {
+			IDLAccount
+			Discriminator []byte `json:"discriminator"`
+		} `json:"accounts"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse IDL: %w", err)
+	}
+
+	idl := raw.IDL
+	if idl.Address == "" {
+		idl.Address = raw.Metadata.Address
+	}
+	if idl.Name == "" {
+		idl.Name = raw.Metadata.Name
+	}
+	idl.Instructions = make([]IDLInstruction, len(raw.Instructions))
+	for i, ix := range raw.Instructions {
+		idl.Instructions[i] = ix.IDLInstruction
+		idl.Instructions[i].Discriminator = ix.Discriminator
+		if ix.Discriminator == nil {
+			sum := sha256.Sum256([]byte("global:" + snakeCase(ix.Name)))
+			idl.Instructions[i].Discriminator = sum[:DiscriminatorSize]
+		}
+	}
+	idl.Accounts = make([]IDLAccount, len(raw.Accounts))
+	for i, acc := range raw.Accounts {
+		idl.Accounts[i] = acc.IDLAccount
+		idl.Accounts[i].Discriminator = acc.Discriminator
+		if acc.Discriminator == nil {
+			disc := AnchorDiscriminator(acc.Name)
+			idl.Accounts[i].Discriminator = disc[:]
+		}
+	}
+
+	idl.types = make(map[string]*IDLTypeDef, len(idl.Types))
+	for i := range idl.Types {
+		idl.types[idl.Types[i].Name] = &idl.Types[i]
+	}
+	return &idl, nil
+}
+
+// DecodeInstruction decodes instruction data, naming the instruction's
+// accounts, given as base58 pubkeys in instruction order
+func (idl *IDL) DecodeInstruction(data []byte, accounts []string) (*DecodedInstruction, error) {
+	for i := range idl.Instructions {
+		ix := &idl.Instructions[i]
+		if len(ix.Discriminator) == 0 || !bytes.HasPrefix(data, ix.Discriminator) {
+			continue
+		}
+
+		r := newReader(data[len(ix.Discriminator):])
+		args, err := idl.decodeFields(r, ix.Args, 0)
+		if err == nil {
+			err = r.err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", idl.Name, ix.Name, err)
+		}
+		return &DecodedInstruction{
+			Program:   idl.Name,
+			ProgramID: idl.Address,
+			Name:      ix.Name,
+			Args:      fieldMap(args),
+			Accounts:  nameAccounts(ix.Accounts, accounts),
+		}, nil
+	}
+	return nil, fmt.Errorf("%w for %s", ErrUnknownInstruction, idl.Name)
+}
+
+// DecodeAccount decodes account data, discriminator included
+func (idl *IDL) DecodeAccount(data []byte) (*DecodedAccount, error) {
+	for i := range idl.Accounts {
+		acc := &idl.Accounts[i]
+		if !bytes.HasPrefix(data, acc.Discriminator) {
+			continue
+		}
+
+		def := acc.Type
+		if def == nil {
+			typ, ok := idl.types[acc.Name]
+			if !ok {
+				return nil, fmt.Errorf("%s: account %s has no type", idl.Name, acc.Name)
+			}
+			def = &typ.Type
+		}
+		r := newReader(data[len(acc.Discriminator):])
+		v, err := idl.decodeDef(r, def, 0)
+		if err == nil {
+			err = r.err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", idl.Name, acc.Name, err)
+		}
+		fields, ok := v.(map[string]any)
+		if !ok {
+			fields = map[string]any{"value": v}
+		}
+		return &DecodedAccount{Program: idl.Name, Name: acc.Name, Fields: fields}, nil
+	}
+	return nil, fmt.Errorf("%w: no %s account matches", ErrDiscriminator, idl.Name)
+}
+
+// RegisterIDL registers decoders for the accounts of an IDL's program and
+// keeps the IDL for DecodeInstruction, replacing any previous IDL of the
+// program and the account decoders it registered. Accounts must have
+// DiscriminatorSize byte discriminators.
+func (r *Registry) RegisterIDL(idl *IDL) error {
+	if idl.Address == "" {
+		return fmt.Errorf("IDL %s has no program address", idl.Name)
+	}
+	for _, acc := range idl.Accounts {
+		if len(acc.Discriminator) != DiscriminatorSize {
+			return fmt.Errorf("account %s of IDL %s has a %d byte discriminator, want %d",
+				acc.Name, idl.Name, len(acc.Discriminator), DiscriminatorSize)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if prev, ok := r.idls[idl.Address]; ok {
+		for _, acc := range prev.Accounts {
+			if len(acc.Discriminator) != DiscriminatorSize {
+				continue
+			}
+			delete(r.decoders, registryKey{owner: idl.Address, discriminator: [DiscriminatorSize]byte(acc.Discriminator)})
+		}
+	}
+	d := AccountDecoderFunc(decodeAs(idl.DecodeAccount))
+	for _, acc := range idl.Accounts {
+		r.decoders[registryKey{owner: idl.Address, discriminator: [DiscriminatorSize]byte(acc.Discriminator)}] = d
+	}
+	r.idls[idl.Address] = idl
+	return nil
+}
+
+// IDL returns the IDL registered for the base58 program ID
+func (r *Registry) IDL(programID string) (*IDL, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	idl, ok := r.idls[programID]
+	return idl, ok
+}
+
+// DecodeInstruction decodes an instruction of the base58 program ID with its
+// registered IDL
+func (r *Registry) DecodeInstruction(programID string, data []byte, accounts []string) (*DecodedInstruction, error) {
+	idl, ok := r.IDL(programID)
+	if !ok {
+		return nil, fmt.Errorf("%w: no IDL for %s", ErrNoDecoder, programID)
+	}
+	return idl.DecodeInstruction(data, accounts)
+}
+
+// decodeFields reads fields in order, returning named fields as a map and
+// tuple fields as a slice
+func (idl *IDL) decodeFields(r *reader, fields []IDLField, depth int) (any, error) {
+	named := len(fields) == 0 || fields[0].Name != ""
+	values := make([]any, 0, len(fields))
+	for _, f := range fields {
+		v, err := idl.decodeType(r, &f.Type, depth)
+		if err == nil {
+			err = r.err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		values = append(values, v)
+	}
+	if !named {
+		return values, nil
+	}
+	m := make(map[string]any, len(fields))
+	for i, f := range fields {
+		m[f.Name] = values[i]
+	}
+	return m, nil
+}
+
+func (idl *IDL) decodeDef(r *reader, def *IDLTypeDefTy, depth int) (any, error) {
+	switch def.Kind {
+	case "struct":
+		return idl.decodeFields(r, def.Fields, depth)
+	case "enum":
+		i := int(r.u8())
+		if r.err != nil {
+			return nil, r.err
+		}
+		if i >= len(def.Variants) {
+			return nil, fmt.Errorf("enum variant %d out of range", i)
+		}
+		variant := def.Variants[i]
+		if len(variant.Fields) == 0 {
+			return variant.Name, nil
+		}
+		v, err := idl.decodeFields(r, variant.Fields, depth)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{variant.Name: v}, nil
+	case "type":
+		if def.Alias != nil {
+			return idl.decodeType(r, def.Alias, depth)
+		}
+	}
+	return nil, fmt.Errorf("%w: type kind %q", ErrUnsupportedType, def.Kind)
+}
+
+func (idl *IDL) decodeType(r *reader, t *IDLType, depth int) (any, error) {
+	if depth > maxTypeDepth {
+		return nil, fmt.Errorf("%w: nested deeper than %d", ErrUnsupportedType, maxTypeDepth)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	depth++
+
+	switch {
+	case t.Unsupported != "":
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, t.Unsupported)
+	case t.Option != nil:
+		if !r.bool() {
+			return nil, nil
+		}
+		return idl.decodeType(r, t.Option, depth)
+	case t.COption != nil:
+		if r.u32() == 0 {
+			return nil, nil
+		}
+		return idl.decodeType(r, t.COption, depth)
+	case t.Vec != nil:
+		n := int(r.u32())
+		if t.Vec.Primitive == "u8" {
+			return r.take(n), r.err
+		}
+		return idl.decodeList(r, t.Vec, n, depth)
+	case t.Array != nil:
+		if t.Array.Primitive == "u8" {
+			return r.take(t.ArrayLen), r.err
+		}
+		return idl.decodeList(r, t.Array, t.ArrayLen, depth)
+	case t.Defined != "":
+		def, ok := idl.types[t.Defined]
+		if !ok {
+			return nil, fmt.Errorf("%w: undefined type %q", ErrUnsupportedType, t.Defined)
+		}
+		return idl.decodeDef(r, &def.Type, depth)
+	}
+
+	switch t.Primitive {
+	case "bool":
+		return r.bool(), nil
+	case "u8":
+		return r.u8(), nil
+	case "i8":
+		return int8(r.u8()), nil
+	case "u16":
+		return r.u16(), nil
+	case "i16":
+		return r.i16(), nil
+	case "u32":
+		return r.u32(), nil
+	case "i32":
+		return r.i32(), nil
+	case "f32":
+		return r.f32(), nil
+	case "u64":
+		return r.u64(), nil
+	case "i64":
+		return r.i64(), nil
+	case "f64":
+		return r.f64(), nil
+	case "u128":
+		return r.u128(), nil
+	case "i128":
+		return signed(r.u128(), 128), nil
+	case "u256":
+		return r.uint(32), nil
+	case "i256":
+		return signed(r.uint(32), 256), nil
+	case "string":
+		return r.string(), nil
+	case "bytes":
+		return r.bytes(), nil
+	case "pubkey", "publicKey":
+		return r.pubkey(), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedType, t.Primitive)
+}
+
+func (idl *IDL) decodeList(r *reader, item *IDLType, n, depth int) ([]any, error) {
+	// Lengths are read from the data; a corrupt one must not spin on
+	// zero-sized items
+	if n > r.remaining()+maxZeroSizedItems {
+		return nil, fmt.Errorf("%w: %d items in %d bytes", ErrShortData, n, r.remaining())
+	}
+	items := make([]any, 0, min(n, r.remaining()))
+	for i := 0; i < n; i++ {
+		v, err := idl.decodeType(r, item, depth)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		items = append(items, v)
+	}
+	return items, nil
+}
+
+// UnmarshalJSON reads a field, which is an object with a name and type, or
+// a bare type in tuples
+func (f *IDLField) UnmarshalJSON(data []byte) error {
+	var named struct {
+		Name *string         `json:"name"`
+		Type json.RawMessage `json:"type"`
+	}
+	if err := json.Unmarshal(data, &named); err == nil && named.Name != nil && named.Type != nil {
+		f.Name = *named.Name
+		return json.Unmarshal(named.Type, &f.Type)
+	}
+	return json.Unmarshal(data, &f.Type)
+}
+
+// UnmarshalJSON reads a type in either IDL format
+func (t *IDLType) UnmarshalJSON(data []byte) error {
+	var primitive string
+	if err := json.Unmarshal(data, &primitive); err == nil {
+		t.Primitive = primitive
+		return nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return fmt.Errorf("IDL type %s: %w", data, err)
+	}
+	switch {
+	case obj["option"] != nil:
+		t.Option = new(IDLType)
+		return json.Unmarshal(obj["option"], t.Option)
+	case obj["coption"] != nil:
+		t.COption = new(IDLType)
+		return json.Unmarshal(obj["coption"], t.COption)
+	case obj["vec"] != nil:
+		t.Vec = new(IDLType)
+		return json.Unmarshal(obj["vec"], t.Vec)
+	case obj["array"] != nil:
+		var array []json.RawMessage
+		if err := json.Unmarshal(obj["array"], &array); err != nil || len(array) != 2 {
+			return fmt.Errorf("IDL array %s: want [type, length]", obj["array"])
+		}
+		t.Array = new(IDLType)
+		if err := json.Unmarshal(array[0], t.Array); err != nil {
+			return err
+		}
+		if err := json.Unmarshal(array[1], &t.ArrayLen); err != nil {
+			t.Unsupported = fmt.Sprintf("array length %s", array[1])
+		}
+		return nil
+	case obj["defined"] != nil:
+		var defined struct {
+			Name     string            `json:"name"`
+			Generics []json.RawMessage `json:"generics"`
+		}
+		if err := json.Unmarshal(obj["defined"], &defined.Name); err != nil {
+			if err := json.Unmarshal(obj["defined"], &defined); err != nil {
+				return err
+			}
+		}
+		t.Defined = defined.Name
+		if len(defined.Generics) > 0 {
+			t.Unsupported = fmt.Sprintf("generic type %s", defined.Name)
+		}
+		return nil
+	default:
+		t.Unsupported = string(data)
+		return nil
+	}
+}
+
+// UnmarshalJSON reads an instruction account in either IDL format
+func (a *IDLInstructionAcc) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Name       string              `json:"name"`
+		Writable   bool                `json:"writable"`
+		Signer     bool                `json:"signer"`
+		Optional   bool                `json:"optional"`
+		IsMut      bool                `json:"isMut"`
+		IsSigner   bool                `json:"isSigner"`
+		IsOptional bool                `json:"isOptional"`
+		Accounts   []IDLInstructionAcc `json:"accounts"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*a = IDLInstructionAcc{
+		Name:     raw.Name,
+		Writable: raw.Writable || raw.IsMut,
+		Signer:   raw.Signer || raw.IsSigner,
+		Optional: raw.Optional || raw.IsOptional,
+		Accounts: raw.Accounts,
+	}
+	return nil
+}
+
+// nameAccounts pairs pubkeys with the IDL's accounts, flattening groups
+func nameAccounts(idlAccounts []IDLInstructionAcc, pubkeys []string) []NamedAccount {
+	var flat []IDLInstructionAcc
+	var flatten func(prefix string, accs []IDLInstructionAcc)
+	flatten = func(prefix string, accs []IDLInstructionAcc) {
+		for _, acc := range accs {
+			if len(acc.Accounts) > 0 {
+				flatten(prefix+acc.Name+".", acc.Accounts)
+				continue
+			}
+			acc.Name = prefix + acc.Name
+			flat = append(flat, acc)
+		}
+	}
+	flatten("", idlAccounts)
+
+	named := make([]NamedAccount, len(pubkeys))
+	for i, pubkey := range pubkeys {
+		named[i] = NamedAccount{Name: "remaining", Pubkey: pubkey}
+		if i < len(flat) {
+			named[i].Name, named[i].Writable, named[i].Signer = flat[i].Name, flat[i].Writable, flat[i].Signer
+		}
+	}
+	return named
+}
+
+// fieldMap returns decoded fields as a map, keying tuple fields by index
+func fieldMap(v any) map[string]any {
+	switch v := v.(type) {
+	case map[string]any:
+		return v
+	case []any:
+		m := make(map[string]any, len(v))
+		for i, item := range v {
+			m[strconv.Itoa(i)] = item
+		}
+		return m
+	default:
+		return map[string]any{}
+	}
+}
+
+// snakeCase converts a legacy camelCase IDL name to the snake_case Anchor
+// hashes into discriminators
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// signed interprets an unsigned little-endian integer of the given bit size
+// as two's complement
+func signed(v *big.Int, bits uint) *big.Int {
+	if v.Bit(int(bits)-1) == 0 {
+		return v
+	}
+	return v.Sub(v, new(big.Int).Lsh(big.NewInt(1), bits))
+}
@@ -0,0 +1,286 @@
+package decoder
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// anchorIDL is an IDL in the format of Anchor 0.30 and later
+const anchorIDL = `{
+  "address": "Demo111111111111111111111111111111111111111",
+  "metadata": {"name": "demo", "version": "0.1.0", "spec": "0.1.0"},
+  "instructions": [{
+    "name": "swap_v2",
+    "discriminator": [1, 2, 3, 4, 5, 6, 7, 8],
+    "accounts": [
+      {"name": "user", "writable": true, "signer": true},
+      {"name": "pool", "accounts": [{"name": "state", "writable": true}, {"name": "vault"}]}
+    ],
+    "args": [
+      {"name": "amount", "type": "u64"},
+      {"name": "side", "type": {"defined": {"name": "Side"}}},
+      {"name": "limit", "type": {"option": "i128"}},
+      {"name": "path", "type": {"vec": "pubkey"}},
+      {"name": "tag", "type": {"array": ["u8", 3]}},
+      {"name": "memo", "type": "string"}
+    ]
+  }],
+  "accounts": [{"name": "Pool", "discriminator": [9, 9, 9, 9, 9, 9, 9, 9]}],
+  "types": [
+    {"name": "Side", "type": {"kind": "enum", "variants": [
+      {"name": "Buy"},
+      {"name": "Sell", "fields": [{"name": "min", "type": "u16"}]},
+      {"name": "Pair", "fields": ["u8", "bool"]}
+    ]}},
+    {"name": "Pool", "type": {"kind": "struct", "fields": [
+      {"name": "authority", "type": "pubkey"},
+      {"name": "fees", "type": {"array": [{"defined": {"name": "Fee"}}, 2]}}
+    ]}},
+    {"name": "Fee", "type": {"kind": "struct", "fields": [{"name": "bps", "type": "u16"}]}}
+  ]
+}`
+
+// legacyIDL is an IDL in the format of Anchor before 0.30, without
+// discriminators
+const legacyIDL = `{
+  "version": "0.1.0",
+  "name": "legacy",
+  "instructions": [{
+    "name": "initializePool",
+    "accounts": [{"name": "payer", "isMut": true, "isSigner": true}],
+    "args": [
+      {"name": "bump", "type": "u8"},
+      {"name": "owner", "type": "publicKey"},
+      {"name": "limit", "type": {"coption": "u32"}},
+      {"name": "data", "type": {"defined": "Data"}}
+    ]
+  }],
+  "accounts": [{"name": "PoolState", "type": {"kind": "struct", "fields": [{"name": "x", "type": "i64"}]}}],
+  "types": [{"name": "Data", "type": {"kind": "struct", "fields": [{"name": "v", "type": {"vec": "u8"}}]}}],
+  "metadata": {"address": "Leg1111111111111111111111111111111111111111"}
+}`
+
+func TestDecodeInstruction(t *testing.T) {
+	swap := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	sum := sha256.Sum256([]byte("global:initialize_pool"))
+	initialize := sum[:DiscriminatorSize]
+	minusOne := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+	tests := []struct {
+		name     string
+		idl      string
+		data     []byte
+		accounts []string
+		want     string // JSON of the decoded instruction
+		err      error
+		// fails expects an error without a sentinel
+		fails bool
+	}{
+		{
+			name: "Anchor 0.30",
+			idl:  anchorIDL,
+			data: concat(swap, le64(500), []byte{1}, le16(42), []byte{1}, minusOne,
+				le32(1), testKey(7), []byte("abc"), borshString("hi")),
+			accounts: []string{"U", "S", "V", "X"},
+			want: `{"program":"demo","program_id":"Demo111111111111111111111111111111111111111","name":"swap_v2",` +
+				`"args":{"amount":500,"limit":-1,"memo":"hi","path":["` + testAddress(7) + `"],"side":{"Sell":{"min":42}},"tag":"YWJj"},` +
+				`"accounts":[{"name":"user","pubkey":"U","writable":true,"signer":true},{"name":"pool.state","pubkey":"S","writable":true},` +
+				`{"name":"pool.vault","pubkey":"V"},{"name":"remaining","pubkey":"X"}]}`,
+		},
+		{
+			name: "tuple variant and empty values",
+			idl:  anchorIDL,
+			data: concat(swap, le64(1), []byte{2, 5, 1}, []byte{0}, le32(0), []byte{0, 0, 0}, le32(0)),
+			want: `{"program":"demo","program_id":"Demo111111111111111111111111111111111111111","name":"swap_v2",` +
+				`"args":{"amount":1,"limit":null,"memo":"","path":[],"side":{"Pair":[5,true]},"tag":"AAAA"},"accounts":[]}`,
+		},
+		{
+			name:     "legacy",
+			idl:      legacyIDL,
+			data:     concat(initialize, []byte{254}, testKey(7), le32(1), le32(77), le32(2), []byte{1, 2}),
+			accounts: []string{"P"},
+			want: `{"program":"legacy","program_id":"Leg1111111111111111111111111111111111111111","name":"initializePool",` +
+				`"args":{"bump":254,"data":{"v":"AQI="},"limit":77,"owner":"` + testAddress(7) + `"},` +
+				`"accounts":[{"name":"payer","pubkey":"P","writable":true,"signer":true}]}`,
+		},
+		{
+			name: "unknown discriminator",
+			idl:  anchorIDL,
+			data: make([]byte, 16),
+			err:  ErrUnknownInstruction,
+		},
+		{
+			name: "truncated",
+			idl:  anchorIDL,
+			data: concat(swap, le64(500), []byte{1}),
+			err:  ErrShortData,
+		},
+		{
+			name: "corrupt vec length",
+			idl:  anchorIDL,
+			data: concat(swap, le64(500), []byte{0, 0}, le32(1<<31)),
+			err:  ErrShortData,
+		},
+		{
+			name:  "enum variant out of range",
+			idl:   anchorIDL,
+			data:  concat(swap, le64(500), []byte{3}),
+			fails: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idl, err := ParseIDL([]byte(tt.idl))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ix, err := idl.DecodeInstruction(tt.data, tt.accounts)
+			switch {
+			case tt.fails:
+				if err == nil {
+					t.Fatalf("DecodeInstruction = %+v, want an error", ix)
+				}
+				return
+			case !errors.Is(err, tt.err):
+				t.Fatalf("DecodeInstruction error = %v, want %v", err, tt.err)
+			case err != nil:
+				return
+			}
+			got, err := json.Marshal(ix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("DecodeInstruction =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeIDLAccount(t *testing.T) {
+	poolState := AnchorDiscriminator("PoolState")
+	tests := []struct {
+		name string
+		idl  string
+		data []byte
+		want string
+	}{
+		{
+			name: "Anchor 0.30",
+			idl:  anchorIDL,
+			data: concat([]byte{9, 9, 9, 9, 9, 9, 9, 9}, testKey(7), le16(10), le16(20)),
+			want: `{"program":"demo","name":"Pool","fields":{"authority":"` + testAddress(7) + `","fees":[{"bps":10},{"bps":20}]}}`,
+		},
+		{
+			name: "legacy with derived discriminator",
+			idl:  legacyIDL,
+			data: concat(poolState[:], le64(1<<64-2)),
+			want: `{"program":"legacy","name":"PoolState","fields":{"x":-2}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idl, err := ParseIDL([]byte(tt.idl))
+			if err != nil {
+				t.Fatal(err)
+			}
+			registry := NewRegistry()
+			if err := registry.RegisterIDL(idl); err != nil {
+				t.Fatal(err)
+			}
+			v, err := registry.Decode(idl.Address, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := json.Marshal(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Decode =\n%s\nwant\n%s", got, tt.want)
+			}
+			if fields := Fields(v); fields == nil {
+				t.Error("Fields returned nil for an IDL account")
+			}
+		})
+	}
+}
+
+func TestRegisterIDL(t *testing.T) {
+	pool := []byte{9, 9, 9, 9, 9, 9, 9, 9}
+	vault := []byte{8, 8, 8, 8, 8, 8, 8, 8}
+	account := func(name string, disc []byte) IDLAccount {
+		return IDLAccount{Name: name, Discriminator: disc, Type: &IDLTypeDefTy{Kind: "struct"}}
+	}
+	const program = "Demo111111111111111111111111111111111111111"
+
+	tests := []struct {
+		name     string
+		accounts [][]IDLAccount
+		fails    bool
+		decodes  [][]byte
+		missing  [][]byte
+	}{
+		{
+			name:     "single",
+			accounts: [][]IDLAccount{{account("Pool", pool), account("Vault", vault)}},
+			decodes:  [][]byte{pool, vault},
+		},
+		{
+			name:     "replacement drops old accounts",
+			accounts: [][]IDLAccount{{account("Pool", pool), account("Vault", vault)}, {account("Pool", pool)}},
+			decodes:  [][]byte{pool},
+			missing:  [][]byte{vault},
+		},
+		{
+			name:     "custom discriminator length",
+			accounts: [][]IDLAccount{{account("Pool", pool), account("Short", []byte{1})}},
+			fails:    true,
+			missing:  [][]byte{pool},
+		},
+		{
+			name:     "failed replacement keeps previous IDL",
+			accounts: [][]IDLAccount{{account("Vault", vault)}, {account("Short", []byte{1, 2, 3, 4})}},
+			fails:    true,
+			decodes:  [][]byte{vault},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			var err error
+			for _, accounts := range tt.accounts {
+				err = registry.RegisterIDL(&IDL{Address: program, Name: "demo", Accounts: accounts})
+			}
+			if (err != nil) != tt.fails {
+				t.Fatalf("RegisterIDL error = %v, want failure %v", err, tt.fails)
+			}
+			for _, disc := range tt.decodes {
+				if _, err := registry.Decode(program, disc); err != nil {
+					t.Errorf("Decode(%v) error = %v", disc, err)
+				}
+			}
+			for _, disc := range tt.missing {
+				if _, err := registry.Decode(program, disc); !errors.Is(err, ErrNoDecoder) {
+					t.Errorf("Decode(%v) error = %v, want ErrNoDecoder", disc, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"initializePool": "initialize_pool",
+		"swapV2":         "swap_v2",
+		"setAMMConfig":   "set_amm_config",
+		"swap":           "swap",
+	}
+	for in, want := range tests {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+
+	"github.com/mr-tron/base58"
+)
+
+// ErrShortData means account or instruction data ended before its layout did
+var ErrShortData = errors.New("data too short for layout")
+
+// reader decodes little-endian and Borsh values, remembering the first error
+// so that a layout can be read without checking each field
+type reader struct {
+	data []byte
+	off  int
+	err  error
+}
+
+func newReader(data []byte) *reader {
+	return &reader{data: data}
+}
+
+// take returns the next n bytes, or zeros once the data is exhausted
+func (r *reader) take(n int) []byte {
+	if r.err != nil || n < 0 || len(r.data)-r.off < n {
+		if r.err == nil {
+			r.err = fmt.Errorf("%w: need %d bytes at offset %d, have %d", ErrShortData, n, r.off, len(r.data)-r.off)
+		}
+		if n > 32 {
+			// Lengths read from corrupt data can be huge
+			return nil
+		}
+		return make([]byte, max(n, 0))
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b
+}
+
+func (r *reader) skip(n int) {
+	r.take(n)
+}
+
+func (r *reader) remaining() int {
+	return len(r.data) - r.off
+}
+
+func (r *reader) u8() uint8 {
+	return r.take(1)[0]
+}
+
+func (r *reader) bool() bool {
+	return r.u8() != 0
+}
+
+func (r *reader) u16() uint16 {
+	return binary.LittleEndian.Uint16(r.take(2))
+}
+
+func (r *reader) u32() uint32 {
+	return binary.LittleEndian.Uint32(r.take(4))
+}
+
+func (r *reader) u64() uint64 {
+	return binary.LittleEndian.Uint64(r.take(8))
+}
+
+func (r *reader) i16() int16 {
+	return int16(r.u16())
+}
+
+func (r *reader) i32() int32 {
+	return int32(r.u32())
+}
+
+func (r *reader) i64() int64 {
+	return int64(r.u64())
+}
+
+func (r *reader) f64() float64 {
+	return math.Float64frombits(r.u64())
+}
+
+// f32 reads a little-endian IEEE 754 single-precision float
+func (r *reader) f32() float32 {
+	return math.Float32frombits(r.u32())
+}
+
+// u128 reads a little-endian 128-bit integer
+func (r *reader) u128() *big.Int {
+	return r.uint(16)
+}
+
+// uint reads an unsigned little-endian integer of size bytes
+func (r *reader) uint(size int) *big.Int {
+	b := slices.Clone(r.take(size))
+	slices.Reverse(b)
+	return new(big.Int).SetBytes(b)
+}
+
+// pubkey reads a 32-byte key as base58
+func (r *reader) pubkey() string {
+	return base58.Encode(r.take(32))
+}
+
+// optionalPubkey reads a 32-byte key that is all zeros when unset, returning
+// "" for the zero key
+func (r *reader) optionalPubkey() string {
+	key := r.take(32)
+	for _, b := range key {
+		if b != 0 {
+			return base58.Encode(key)
+		}
+	}
+	return ""
+}
+
+// coptionPubkey reads a C-style COption<Pubkey>: a 4-byte tag followed by the
+// key, which is present even when unset. It returns "" for None.
+func (r *reader) coptionPubkey() string {
+	tag := r.u32()
+	key := r.pubkey()
+	if tag == 0 {
+		return ""
+	}
+	return key
+}
+
+// string reads a Borsh string, a u32 length followed by UTF-8 bytes
+func (r *reader) string() string {
+	return string(r.take(int(r.u32())))
+}
+
+// bytes reads a Borsh Vec<u8>
+func (r *reader) bytes() []byte {
+	return r.take(int(r.u32()))
+}
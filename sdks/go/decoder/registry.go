@@ -0,0 +1,166 @@
+package decoder
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// DiscriminatorSize is the length of an Anchor account discriminator
+const DiscriminatorSize = 8
+
+// ErrNoDecoder means no decoder is registered for an account's owner and
+// discriminator
+var ErrNoDecoder = errors.New("no decoder registered")
+
+// AccountDecoder decodes account data, discriminator included, into a typed
+// value
+type AccountDecoder interface {
+	Decode(data []byte) (any, error)
+}
+
+// AccountDecoderFunc adapts a function to AccountDecoder
+type AccountDecoderFunc func(data []byte) (any, error)
+
+// Decode calls f(data)
+func (f AccountDecoderFunc) Decode(data []byte) (any, error) {
+	return f(data)
+}
+
+// AnchorDiscriminator returns the discriminator Anchor prefixes to accounts
+// of the named type, the first bytes of sha256("account:<name>")
+func AnchorDiscriminator(name string) [DiscriminatorSize]byte {
+	sum := sha256.Sum256([]byte("account:" + name))
+	return [DiscriminatorSize]byte(sum[:DiscriminatorSize])
+}
+
+// Registry maps account owners and discriminators to decoders. It is safe
+// for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	decoders map[registryKey]AccountDecoder
+	idls     map[string]*IDL
+}
+
+type registryKey struct {
+	owner         string
+	discriminator [DiscriminatorSize]byte
+	any           bool
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		decoders: make(map[registryKey]AccountDecoder),
+		idls:     make(map[string]*IDL),
+	}
+}
+
+// DefaultRegistry returns a registry with the built-in decoders: SPL Token,
+// Token-2022, Raydium AMM v4, CLMM and CPMM, Orca Whirlpool, Meteora DLMM and
+// Pump.fun bonding curves
+func DefaultRegistry() *Registry {
+	r := NewRegistry()
+	for _, owner := range []string{TokenProgramID, Token2022ProgramID} {
+		r.RegisterProgram(owner, AccountDecoderFunc(func(data []byte) (any, error) {
+			return DecodeToken(owner, data)
+		}))
+	}
+	r.RegisterProgram(RaydiumAMMV4ProgramID, AccountDecoderFunc(decodeAs(DecodeRaydiumAMMV4Pool)))
+	r.Register(RaydiumCLMMProgramID, AnchorDiscriminator("PoolState"), AccountDecoderFunc(decodeAs(DecodeRaydiumCLMMPool)))
+	r.Register(RaydiumCPMMProgramID, AnchorDiscriminator("PoolState"), AccountDecoderFunc(decodeAs(DecodeRaydiumCPMMPool)))
+	r.Register(OrcaWhirlpoolProgramID, AnchorDiscriminator("Whirlpool"), AccountDecoderFunc(decodeAs(DecodeWhirlpool)))
+	r.Register(MeteoraDLMMProgramID, AnchorDiscriminator("LbPair"), AccountDecoderFunc(decodeAs(DecodeMeteoraDLMMPair)))
+	r.Register(PumpFunProgramID, AnchorDiscriminator("BondingCurve"), AccountDecoderFunc(decodeAs(DecodePumpBondingCurve)))
+	return r
+}
+
+// Register sets the decoder for accounts of the base58 owner that start with
+// discriminator, replacing any previous one
+func (r *Registry) Register(owner string, discriminator [DiscriminatorSize]byte, d AccountDecoder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.decoders[registryKey{owner: owner, discriminator: discriminator}] = d
+}
+
+// RegisterProgram sets the decoder for accounts of the base58 owner whose
+// discriminator has no decoder of its own, for programs without Anchor
+// discriminators
+func (r *Registry) RegisterProgram(owner string, d AccountDecoder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.decoders[registryKey{owner: owner, any: true}] = d
+}
+
+// Lookup returns the decoder for an account of the base58 owner
+func (r *Registry) Lookup(owner string, data []byte) (AccountDecoder, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(data) >= DiscriminatorSize {
+		key := registryKey{owner: owner, discriminator: [DiscriminatorSize]byte(data)}
+		if d, ok := r.decoders[key]; ok {
+			return d, true
+		}
+	}
+	d, ok := r.decoders[registryKey{owner: owner, any: true}]
+	return d, ok
+}
+
+// Decode decodes the data of an account owned by the base58 owner
+func (r *Registry) Decode(owner string, data []byte) (any, error) {
+	d, ok := r.Lookup(owner, data)
+	if !ok {
+		return nil, fmt.Errorf("%w for %d bytes owned by %s", ErrNoDecoder, len(data), owner)
+	}
+	return d.Decode(data)
+}
+
+// Layout returns a function decoding the accounts of the base58 owner into
+// their fields, usable as a thorclient.LayoutDecoder for account diffs
+func (r *Registry) Layout(owner string) func(data []byte) (map[string]any, error) {
+	return func(data []byte) (map[string]any, error) {
+		v, err := r.Decode(owner, data)
+		if err != nil {
+			return nil, err
+		}
+		return Fields(v), nil
+	}
+}
+
+// Fields returns the exported fields of a decoded struct, or pointer to one,
+// by name, and the fields of an account decoded with an IDL. Other values
+// are returned under "value".
+func Fields(v any) map[string]any {
+	if acc, ok := v.(*DecodedAccount); ok {
+		return acc.Fields
+	}
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return map[string]any{"value": v}
+	}
+
+	fields := make(map[string]any, rv.NumField())
+	for i := 0; i < rv.NumField(); i++ {
+		if f := rv.Type().Field(i); f.IsExported() {
+			fields[f.Name] = rv.Field(i).Interface()
+		}
+	}
+	return fields
+}
+
+// decodeAs adapts a typed decode function to AccountDecoderFunc
+func decodeAs[T any](decode func([]byte) (T, error)) func([]byte) (any, error) {
+	return func(data []byte) (any, error) {
+		v, err := decode(data)
+		if err != nil {
+			return nil, err
+		}
+		return v, nil
+	}
+}
@@ -0,0 +1,240 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Program IDs of the token programs
+const (
+	TokenProgramID     = "TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA"
+	Token2022ProgramID = "TokenzQdBNbLqP5VEhdkAS6EPFLC1PHnBqCXEpPxuEb"
+)
+
+// Sizes of the token program account layouts without extensions
+const (
+	MintSize         = 82
+	TokenAccountSize = 165
+	MultisigSize     = 355
+)
+
+// maxSigners is the number of signer slots in a multisig account
+const maxSigners = 11
+
+var (
+	// ErrNotTokenProgram means the account is not owned by a token program
+	ErrNotTokenProgram = errors.New("account is not owned by a token program")
+	// ErrUnknownLayout means the data matches none of the owner's layouts
+	ErrUnknownLayout = errors.New("unknown account layout")
+)
+
+// AccountState is the state of a token account
+type AccountState uint8
+
+const (
+	StateUninitialized AccountState = iota
+	StateInitialized
+	StateFrozen
+)
+
+func (s AccountState) String() string {
+	switch s {
+	case StateUninitialized:
+		return "uninitialized"
+	case StateInitialized:
+		return "initialized"
+	case StateFrozen:
+		return "frozen"
+	default:
+		return fmt.Sprintf("AccountState(%d)", uint8(s))
+	}
+}
+
+// Mint is a token mint. Authorities are base58, or "" when unset.
+type Mint struct {
+	MintAuthority   string
+	Supply          uint64
+	Decimals        uint8
+	IsInitialized   bool
+	FreezeAuthority string
+	// Extensions are the Token-2022 extensions of the mint
+	Extensions []Extension
+}
+
+// UISupply returns the supply in whole tokens
+func (m *Mint) UISupply() string {
+	return UIAmountString(m.Supply, m.Decimals)
+}
+
+// TokenAccount is an account holding tokens of one mint. Keys are base58, or
+// "" when unset.
+type TokenAccount struct {
+	Mint     string
+	Owner    string
+	Amount   uint64
+	Delegate string
+	State    AccountState
+	// IsNative is set for wrapped SOL accounts, which keep RentExemptReserve
+	// lamports on top of Amount
+	IsNative          bool
+	RentExemptReserve uint64
+	DelegatedAmount   uint64
+	CloseAuthority    string
+	// Extensions are the Token-2022 extensions of the account
+	Extensions []Extension
+}
+
+// UIAmount returns the balance in whole tokens given the mint's decimals
+func (a *TokenAccount) UIAmount(decimals uint8) string {
+	return UIAmountString(a.Amount, decimals)
+}
+
+// Multisig is an m-of-n signer set usable as a token authority
+type Multisig struct {
+	M             uint8
+	N             uint8
+	IsInitialized bool
+	Signers       []string
+}
+
+// IsTokenProgram reports whether the base58 owner is a token program
+func IsTokenProgram(owner string) bool {
+	return owner == TokenProgramID || owner == Token2022ProgramID
+}
+
+// DecodeToken decodes the data of an account owned by the base58 owner into a
+// *Mint, *TokenAccount or *Multisig
+func DecodeToken(owner string, data []byte) (any, error) {
+	switch owner {
+	case TokenProgramID:
+		switch len(data) {
+		case MintSize:
+			return DecodeMint(data)
+		case TokenAccountSize:
+			return DecodeTokenAccount(data)
+		case MultisigSize:
+			return DecodeMultisig(data)
+		}
+	case Token2022ProgramID:
+		switch {
+		case len(data) == MultisigSize:
+			// Token-2022 pads extended accounts that would have this size
+			return DecodeMultisig(data)
+		case len(data) == MintSize:
+			return DecodeMint(data)
+		case len(data) == TokenAccountSize:
+			return DecodeTokenAccount(data)
+		case len(data) > TokenAccountSize:
+			switch accountType(data[TokenAccountSize]) {
+			case accountTypeMint:
+				return DecodeMint(data)
+			case accountTypeAccount:
+				return DecodeTokenAccount(data)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrNotTokenProgram, owner)
+	}
+	return nil, fmt.Errorf("%w: %d bytes owned by %s", ErrUnknownLayout, len(data), owner)
+}
+
+// DecodeMint decodes a mint, including Token-2022 extensions
+func DecodeMint(data []byte) (*Mint, error) {
+	r := newReader(data)
+	m := &Mint{
+		MintAuthority:   r.coptionPubkey(),
+		Supply:          r.u64(),
+		Decimals:        r.u8(),
+		IsInitialized:   r.bool(),
+		FreezeAuthority: r.coptionPubkey(),
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("mint: %w", r.err)
+	}
+	if len(data) > MintSize {
+		exts, err := decodeExtensions(data, accountTypeMint)
+		if err != nil {
+			return nil, fmt.Errorf("mint: %w", err)
+		}
+		m.Extensions = exts
+	}
+	return m, nil
+}
+
+// DecodeTokenAccount decodes a token account, including Token-2022 extensions
+func DecodeTokenAccount(data []byte) (*TokenAccount, error) {
+	r := newReader(data)
+	a := &TokenAccount{
+		Mint:     r.pubkey(),
+		Owner:    r.pubkey(),
+		Amount:   r.u64(),
+		Delegate: r.coptionPubkey(),
+		State:    AccountState(r.u8()),
+	}
+	if r.u32() != 0 {
+		a.IsNative = true
+		a.RentExemptReserve = r.u64()
+	} else {
+		r.skip(8)
+	}
+	a.DelegatedAmount = r.u64()
+	a.CloseAuthority = r.coptionPubkey()
+	if r.err != nil {
+		return nil, fmt.Errorf("token account: %w", r.err)
+	}
+	if len(data) > TokenAccountSize {
+		exts, err := decodeExtensions(data, accountTypeAccount)
+		if err != nil {
+			return nil, fmt.Errorf("token account: %w", err)
+		}
+		a.Extensions = exts
+	}
+	return a, nil
+}
+
+// DecodeMultisig decodes a multisig account
+func DecodeMultisig(data []byte) (*Multisig, error) {
+	r := newReader(data)
+	m := &Multisig{
+		M:             r.u8(),
+		N:             r.u8(),
+		IsInitialized: r.bool(),
+	}
+	for i := 0; i < maxSigners; i++ {
+		signer := r.pubkey()
+		if i < int(m.N) {
+			m.Signers = append(m.Signers, signer)
+		}
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("multisig: %w", r.err)
+	}
+	return m, nil
+}
+
+// UIAmountString formats a raw token amount in whole tokens, without
+// trailing zeros
+func UIAmountString(amount uint64, decimals uint8) string {
+	s := fmt.Sprintf("%0*d", int(decimals)+1, amount)
+	if decimals == 0 {
+		return s
+	}
+	point := len(s) - int(decimals)
+	frac := strings.TrimRight(s[point:], "0")
+	if frac == "" {
+		return s[:point]
+	}
+	return s[:point] + "." + frac
+}
+
+// UIAmount converts a raw token amount to whole tokens
+func UIAmount(amount uint64, decimals uint8) float64 {
+	return scaleAmount(amount, decimals, 1)
+}
+
+// scaleAmount returns amount times scale in whole tokens
+func scaleAmount(amount uint64, decimals uint8, scale float64) float64 {
+	return float64(amount) * scale / math.Pow10(int(decimals))
+}
@@ -0,0 +1,432 @@
+package decoder
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// accountType is the byte after the base layout that tells Token-2022 mints
+// and accounts with extensions apart
+type accountType uint8
+
+const (
+	accountTypeUninitialized accountType = iota
+	accountTypeMint
+	accountTypeAccount
+)
+
+// secondsPerYear is the year length used by the interest-bearing extension
+const secondsPerYear = 60 * 60 * 24 * 365.24
+
+// ExtensionType identifies a Token-2022 extension
+type ExtensionType uint16
+
+const (
+	ExtensionUninitialized ExtensionType = iota
+	ExtensionTransferFeeConfig
+	ExtensionTransferFeeAmount
+	ExtensionMintCloseAuthority
+	ExtensionConfidentialTransferMint
+	ExtensionConfidentialTransferAccount
+	ExtensionDefaultAccountState
+	ExtensionImmutableOwner
+	ExtensionMemoTransfer
+	ExtensionNonTransferable
+	ExtensionInterestBearingConfig
+	ExtensionCpiGuard
+	ExtensionPermanentDelegate
+	ExtensionNonTransferableAccount
+	ExtensionTransferHook
+	ExtensionTransferHookAccount
+	ExtensionConfidentialTransferFeeConfig
+	ExtensionConfidentialTransferFeeAmount
+	ExtensionMetadataPointer
+	ExtensionTokenMetadata
+	ExtensionGroupPointer
+	ExtensionTokenGroup
+	ExtensionGroupMemberPointer
+	ExtensionTokenGroupMember
+	ExtensionConfidentialMintBurn
+	ExtensionScaledUIAmount
+	ExtensionPausable
+	ExtensionPausableAccount
+)
+
+var extensionNames = [...]string{
+	"uninitialized",
+	"transferFeeConfig",
+	"transferFeeAmount",
+	"mintCloseAuthority",
+	"confidentialTransferMint",
+	"confidentialTransferAccount",
+	"defaultAccountState",
+	"immutableOwner",
+	"memoTransfer",
+	"nonTransferable",
+	"interestBearingConfig",
+	"cpiGuard",
+	"permanentDelegate",
+	"nonTransferableAccount",
+	"transferHook",
+	"transferHookAccount",
+	"confidentialTransferFeeConfig",
+	"confidentialTransferFeeAmount",
+	"metadataPointer",
+	"tokenMetadata",
+	"groupPointer",
+	"tokenGroup",
+	"groupMemberPointer",
+	"tokenGroupMember",
+	"confidentialMintBurn",
+	"scaledUiAmountConfig",
+	"pausableConfig",
+	"pausableAccount",
+}
+
+func (t ExtensionType) String() string {
+	if int(t) < len(extensionNames) {
+		return extensionNames[t]
+	}
+	return fmt.Sprintf("ExtensionType(%d)", uint16(t))
+}
+
+// Extension is a decoded Token-2022 extension. Extensions without a typed
+// decoder are returned as *RawExtension.
+type Extension interface {
+	Type() ExtensionType
+}
+
+// RawExtension is an extension kept as its undecoded value
+type RawExtension struct {
+	ExtensionType ExtensionType
+	Data          []byte
+}
+
+// TransferFee is a fee schedule effective from Epoch
+type TransferFee struct {
+	Epoch       uint64
+	MaximumFee  uint64
+	BasisPoints uint16
+}
+
+// Fee returns the fee charged on a transfer of amount
+func (f TransferFee) Fee(amount uint64) uint64 {
+	if f.BasisPoints == 0 || amount == 0 {
+		return 0
+	}
+	// Rounded up, as the program does
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(amount), big.NewInt(int64(f.BasisPoints)))
+	fee.Add(fee, big.NewInt(9_999)).Quo(fee, big.NewInt(10_000))
+	if !fee.IsUint64() {
+		return f.MaximumFee
+	}
+	return min(fee.Uint64(), f.MaximumFee)
+}
+
+// TransferFeeConfig is the mint's transfer fee configuration
+type TransferFeeConfig struct {
+	ConfigAuthority   string
+	WithdrawAuthority string
+	WithheldAmount    uint64
+	OlderTransferFee  TransferFee
+	NewerTransferFee  TransferFee
+}
+
+// FeeAt returns the fee schedule in effect at epoch
+func (c *TransferFeeConfig) FeeAt(epoch uint64) TransferFee {
+	if epoch >= c.NewerTransferFee.Epoch {
+		return c.NewerTransferFee
+	}
+	return c.OlderTransferFee
+}
+
+// TransferFeeAmount is the fee withheld in a token account
+type TransferFeeAmount struct {
+	WithheldAmount uint64
+}
+
+// MintCloseAuthority may close the mint once its supply is zero
+type MintCloseAuthority struct {
+	CloseAuthority string
+}
+
+// DefaultAccountState is the state new token accounts of the mint start in
+type DefaultAccountState struct {
+	State AccountState
+}
+
+// ImmutableOwner marks a token account whose owner cannot change
+type ImmutableOwner struct{}
+
+// MemoTransfer requires memos on incoming transfers
+type MemoTransfer struct {
+	RequireIncomingTransferMemos bool
+}
+
+// NonTransferable marks a mint whose tokens cannot be transferred
+type NonTransferable struct{}
+
+// NonTransferableAccount marks a token account of a non-transferable mint
+type NonTransferableAccount struct{}
+
+// InterestBearingConfig accrues continuous interest on the UI amount. Rates
+// are in basis points per year.
+type InterestBearingConfig struct {
+	RateAuthority           string
+	InitializationTimestamp int64
+	PreUpdateAverageRate    int16
+	LastUpdateTimestamp     int64
+	CurrentRate             int16
+}
+
+// UIAmount returns amount in whole tokens with the interest accrued up to
+// unixTime
+func (c *InterestBearingConfig) UIAmount(amount uint64, decimals uint8, unixTime int64) float64 {
+	pre := float64(c.PreUpdateAverageRate) * float64(c.LastUpdateTimestamp-c.InitializationTimestamp)
+	post := float64(c.CurrentRate) * float64(unixTime-c.LastUpdateTimestamp)
+	return scaleAmount(amount, decimals, math.Exp((pre+post)/secondsPerYear/10_000))
+}
+
+// CpiGuard restricts what programs may do with the account through CPI
+type CpiGuard struct {
+	LockCpi bool
+}
+
+// PermanentDelegate may transfer or burn any account's tokens of the mint
+type PermanentDelegate struct {
+	Delegate string
+}
+
+// TransferHook is the program invoked on every transfer of the mint
+type TransferHook struct {
+	Authority string
+	ProgramID string
+}
+
+// TransferHookAccount flags a token account during a hooked transfer
+type TransferHookAccount struct {
+	Transferring bool
+}
+
+// MetadataPointer is the account holding the mint's metadata
+type MetadataPointer struct {
+	Authority       string
+	MetadataAddress string
+}
+
+// TokenMetadata is metadata stored in the mint itself
+type TokenMetadata struct {
+	UpdateAuthority    string
+	Mint               string
+	Name               string
+	Symbol             string
+	URI                string
+	AdditionalMetadata [][2]string
+}
+
+// GroupPointer is the account holding the mint's group configuration
+type GroupPointer struct {
+	Authority    string
+	GroupAddress string
+}
+
+// TokenGroup is a group configuration stored in the mint
+type TokenGroup struct {
+	UpdateAuthority string
+	Mint            string
+	Size            uint64
+	MaxSize         uint64
+}
+
+// GroupMemberPointer is the account holding the mint's group membership
+type GroupMemberPointer struct {
+	Authority     string
+	MemberAddress string
+}
+
+// TokenGroupMember is a group membership stored in the mint
+type TokenGroupMember struct {
+	Mint         string
+	Group        string
+	MemberNumber uint64
+}
+
+// ScaledUIAmount multiplies the UI amount, switching to NewMultiplier at
+// NewMultiplierEffectiveTimestamp
+type ScaledUIAmount struct {
+	Authority                       string
+	Multiplier                      float64
+	NewMultiplierEffectiveTimestamp int64
+	NewMultiplier                   float64
+}
+
+// UIAmount returns amount in whole tokens with the multiplier in effect at
+// unixTime
+func (c *ScaledUIAmount) UIAmount(amount uint64, decimals uint8, unixTime int64) float64 {
+	multiplier := c.Multiplier
+	if unixTime >= c.NewMultiplierEffectiveTimestamp {
+		multiplier = c.NewMultiplier
+	}
+	return scaleAmount(amount, decimals, multiplier)
+}
+
+// Pausable lets its authority pause all activity of the mint
+type Pausable struct {
+	Authority string
+	Paused    bool
+}
+
+// PausableAccount marks a token account of a pausable mint
+type PausableAccount struct{}
+
+func (e *RawExtension) Type() ExtensionType         { return e.ExtensionType }
+func (*TransferFeeConfig) Type() ExtensionType      { return ExtensionTransferFeeConfig }
+func (*TransferFeeAmount) Type() ExtensionType      { return ExtensionTransferFeeAmount }
+func (*MintCloseAuthority) Type() ExtensionType     { return ExtensionMintCloseAuthority }
+func (*DefaultAccountState) Type() ExtensionType    { return ExtensionDefaultAccountState }
+func (*ImmutableOwner) Type() ExtensionType         { return ExtensionImmutableOwner }
+func (*MemoTransfer) Type() ExtensionType           { return ExtensionMemoTransfer }
+func (*NonTransferable) Type() ExtensionType        { return ExtensionNonTransferable }
+func (*NonTransferableAccount) Type() ExtensionType { return ExtensionNonTransferableAccount }
+func (*InterestBearingConfig) Type() ExtensionType  { return ExtensionInterestBearingConfig }
+func (*CpiGuard) Type() ExtensionType               { return ExtensionCpiGuard }
+func (*PermanentDelegate) Type() ExtensionType      { return ExtensionPermanentDelegate }
+func (*TransferHook) Type() ExtensionType           { return ExtensionTransferHook }
+func (*TransferHookAccount) Type() ExtensionType    { return ExtensionTransferHookAccount }
+func (*MetadataPointer) Type() ExtensionType        { return ExtensionMetadataPointer }
+func (*TokenMetadata) Type() ExtensionType          { return ExtensionTokenMetadata }
+func (*GroupPointer) Type() ExtensionType           { return ExtensionGroupPointer }
+func (*TokenGroup) Type() ExtensionType             { return ExtensionTokenGroup }
+func (*GroupMemberPointer) Type() ExtensionType     { return ExtensionGroupMemberPointer }
+func (*TokenGroupMember) Type() ExtensionType       { return ExtensionTokenGroupMember }
+func (*ScaledUIAmount) Type() ExtensionType         { return ExtensionScaledUIAmount }
+func (*Pausable) Type() ExtensionType               { return ExtensionPausable }
+func (*PausableAccount) Type() ExtensionType        { return ExtensionPausableAccount }
+
+// FindExtension returns the first extension of type E, such as
+// *TransferFeeConfig
+func FindExtension[E Extension](exts []Extension) (E, bool) {
+	for _, ext := range exts {
+		if e, ok := ext.(E); ok {
+			return e, true
+		}
+	}
+	var zero E
+	return zero, false
+}
+
+// decodeExtensions parses the TLV extension area after the base layout of a
+// Token-2022 mint or account
+func decodeExtensions(data []byte, want accountType) ([]Extension, error) {
+	if len(data) <= TokenAccountSize {
+		return nil, fmt.Errorf("%w: %d bytes is too short for extensions", ErrUnknownLayout, len(data))
+	}
+	if got := accountType(data[TokenAccountSize]); got != want {
+		return nil, fmt.Errorf("%w: account type %d, expected %d", ErrUnknownLayout, got, want)
+	}
+
+	var exts []Extension
+	r := newReader(data[TokenAccountSize+1:])
+	for r.remaining() >= 4 {
+		typ := ExtensionType(r.u16())
+		value := r.take(int(r.u16()))
+		if r.err != nil {
+			return exts, r.err
+		}
+		if typ == ExtensionUninitialized {
+			// The rest of the area is unused
+			break
+		}
+		ext, err := decodeExtension(typ, value)
+		if err != nil {
+			return exts, fmt.Errorf("%s extension: %w", typ, err)
+		}
+		exts = append(exts, ext)
+	}
+	return exts, nil
+}
+
+func decodeExtension(typ ExtensionType, value []byte) (Extension, error) {
+	r := newReader(value)
+	var ext Extension
+	switch typ {
+	case ExtensionTransferFeeConfig:
+		ext = &TransferFeeConfig{
+			ConfigAuthority:   r.optionalPubkey(),
+			WithdrawAuthority: r.optionalPubkey(),
+			WithheldAmount:    r.u64(),
+			OlderTransferFee:  TransferFee{Epoch: r.u64(), MaximumFee: r.u64(), BasisPoints: r.u16()},
+			NewerTransferFee:  TransferFee{Epoch: r.u64(), MaximumFee: r.u64(), BasisPoints: r.u16()},
+		}
+	case ExtensionTransferFeeAmount:
+		ext = &TransferFeeAmount{WithheldAmount: r.u64()}
+	case ExtensionMintCloseAuthority:
+		ext = &MintCloseAuthority{CloseAuthority: r.optionalPubkey()}
+	case ExtensionDefaultAccountState:
+		ext = &DefaultAccountState{State: AccountState(r.u8())}
+	case ExtensionImmutableOwner:
+		ext = &ImmutableOwner{}
+	case ExtensionMemoTransfer:
+		ext = &MemoTransfer{RequireIncomingTransferMemos: r.bool()}
+	case ExtensionNonTransferable:
+		ext = &NonTransferable{}
+	case ExtensionNonTransferableAccount:
+		ext = &NonTransferableAccount{}
+	case ExtensionInterestBearingConfig:
+		ext = &InterestBearingConfig{
+			RateAuthority:           r.optionalPubkey(),
+			InitializationTimestamp: r.i64(),
+			PreUpdateAverageRate:    r.i16(),
+			LastUpdateTimestamp:     r.i64(),
+			CurrentRate:             r.i16(),
+		}
+	case ExtensionCpiGuard:
+		ext = &CpiGuard{LockCpi: r.bool()}
+	case ExtensionPermanentDelegate:
+		ext = &PermanentDelegate{Delegate: r.optionalPubkey()}
+	case ExtensionTransferHook:
+		ext = &TransferHook{Authority: r.optionalPubkey(), ProgramID: r.optionalPubkey()}
+	case ExtensionTransferHookAccount:
+		ext = &TransferHookAccount{Transferring: r.bool()}
+	case ExtensionMetadataPointer:
+		ext = &MetadataPointer{Authority: r.optionalPubkey(), MetadataAddress: r.optionalPubkey()}
+	case ExtensionTokenMetadata:
+		m := &TokenMetadata{
+			UpdateAuthority: r.optionalPubkey(),
+			Mint:            r.pubkey(),
+			Name:            r.string(),
+			Symbol:          r.string(),
+			URI:             r.string(),
+		}
+		for n := r.u32(); n > 0 && r.err == nil; n-- {
+			m.AdditionalMetadata = append(m.AdditionalMetadata, [2]string{r.string(), r.string()})
+		}
+		ext = m
+	case ExtensionGroupPointer:
+		ext = &GroupPointer{Authority: r.optionalPubkey(), GroupAddress: r.optionalPubkey()}
+	case ExtensionTokenGroup:
+		ext = &TokenGroup{UpdateAuthority: r.optionalPubkey(), Mint: r.pubkey(), Size: r.u64(), MaxSize: r.u64()}
+	case ExtensionGroupMemberPointer:
+		ext = &GroupMemberPointer{Authority: r.optionalPubkey(), MemberAddress: r.optionalPubkey()}
+	case ExtensionTokenGroupMember:
+		ext = &TokenGroupMember{Mint: r.pubkey(), Group: r.pubkey(), MemberNumber: r.u64()}
+	case ExtensionScaledUIAmount:
+		ext = &ScaledUIAmount{
+			Authority:                       r.optionalPubkey(),
+			Multiplier:                      r.f64(),
+			NewMultiplierEffectiveTimestamp: r.i64(),
+			NewMultiplier:                   r.f64(),
+		}
+	case ExtensionPausable:
+		ext = &Pausable{Authority: r.optionalPubkey(), Paused: r.bool()}
+	case ExtensionPausableAccount:
+		ext = &PausableAccount{}
+	default:
+		return &RawExtension{ExtensionType: typ, Data: value}, nil
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return ext, nil
+}
@@ -0,0 +1,209 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/mr-tron/base58"
+)
+
+// Helpers building little-endian account data for the layout tests
+
+func le16(v uint16) []byte { return binary.LittleEndian.AppendUint16(nil, v) }
+func le32(v uint32) []byte { return binary.LittleEndian.AppendUint32(nil, v) }
+func le64(v uint64) []byte { return binary.LittleEndian.AppendUint64(nil, v) }
+
+// testKey returns a 32-byte key starting with b, all zeros for b == 0
+func testKey(b byte) []byte {
+	key := make([]byte, 32)
+	key[0] = b
+	return key
+}
+
+// testAddress is the base58 form of testKey(b)
+func testAddress(b byte) string {
+	return base58.Encode(testKey(b))
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// borshString encodes s as a u32 length followed by its bytes
+func borshString(s string) []byte {
+	return append(le32(uint32(len(s))), s...)
+}
+
+// tlv encodes one Token-2022 extension entry
+func tlv(typ ExtensionType, value []byte) []byte {
+	return concat(le16(uint16(typ)), le16(uint16(len(value))), value)
+}
+
+func TestDecodeToken(t *testing.T) {
+	mint := concat(le32(1), testKey(1), le64(10_000_000_000), []byte{9, 1}, le32(0), testKey(0))
+	account := concat(testKey(2), testKey(3), le64(1_234_500), le32(0), testKey(0), []byte{1},
+		le32(0), le64(0), le64(0), le32(1), testKey(4))
+	native := concat(testKey(2), testKey(3), le64(5), le32(1), testKey(5), []byte{2},
+		le32(1), le64(2_039_280), le64(5), le32(0), testKey(0))
+	multisig := concat([]byte{2, 3, 1}, testKey(6), testKey(7), testKey(8), make([]byte, 32*8))
+
+	// Token-2022 accounts pad the base mint to the account size, then add the
+	// account type and the extensions
+	extMint := concat(mint, make([]byte, TokenAccountSize-MintSize), []byte{byte(accountTypeMint)},
+		tlv(ExtensionTransferFeeConfig, concat(testKey(1), testKey(0), le64(7),
+			le64(1), le64(1000), le16(50), le64(3), le64(500), le16(100))),
+		tlv(ExtensionMetadataPointer, concat(testKey(1), testKey(9))),
+		tlv(ExtensionTokenMetadata, concat(testKey(1), testKey(9), borshString("Thor"), borshString("THR"),
+			borshString("https://example.com"), le32(1), borshString("k"), borshString("v"))),
+		tlv(99, []byte{1, 2}),
+	)
+	extAccount := concat(account, []byte{byte(accountTypeAccount)},
+		tlv(ExtensionImmutableOwner, nil),
+		tlv(ExtensionTransferFeeAmount, le64(42)),
+		// Unused space after the last extension
+		make([]byte, 8),
+	)
+
+	tests := []struct {
+		name  string
+		owner string
+		data  []byte
+		want  any
+		err   error
+	}{
+		{
+			name:  "mint",
+			owner: TokenProgramID,
+			data:  mint,
+			want:  &Mint{MintAuthority: testAddress(1), Supply: 10_000_000_000, Decimals: 9, IsInitialized: true},
+		},
+		{
+			name:  "token account",
+			owner: TokenProgramID,
+			data:  account,
+			want: &TokenAccount{Mint: testAddress(2), Owner: testAddress(3), Amount: 1_234_500,
+				State: StateInitialized, CloseAuthority: testAddress(4)},
+		},
+		{
+			name:  "wrapped SOL account",
+			owner: Token2022ProgramID,
+			data:  native,
+			want: &TokenAccount{Mint: testAddress(2), Owner: testAddress(3), Amount: 5, Delegate: testAddress(5),
+				State: StateFrozen, IsNative: true, RentExemptReserve: 2_039_280, DelegatedAmount: 5},
+		},
+		{
+			name:  "multisig",
+			owner: TokenProgramID,
+			data:  multisig,
+			want:  &Multisig{M: 2, N: 3, IsInitialized: true, Signers: []string{testAddress(6), testAddress(7), testAddress(8)}},
+		},
+		{
+			name:  "Token-2022 mint with extensions",
+			owner: Token2022ProgramID,
+			data:  extMint,
+			want: &Mint{MintAuthority: testAddress(1), Supply: 10_000_000_000, Decimals: 9, IsInitialized: true,
+				Extensions: []Extension{
+					&TransferFeeConfig{
+						ConfigAuthority:  testAddress(1),
+						WithheldAmount:   7,
+						OlderTransferFee: TransferFee{Epoch: 1, MaximumFee: 1000, BasisPoints: 50},
+						NewerTransferFee: TransferFee{Epoch: 3, MaximumFee: 500, BasisPoints: 100},
+					},
+					&MetadataPointer{Authority: testAddress(1), MetadataAddress: testAddress(9)},
+					&TokenMetadata{UpdateAuthority: testAddress(1), Mint: testAddress(9), Name: "Thor", Symbol: "THR",
+						URI: "https://example.com", AdditionalMetadata: [][2]string{{"k", "v"}}},
+					&RawExtension{ExtensionType: 99, Data: []byte{1, 2}},
+				}},
+		},
+		{
+			name:  "Token-2022 account with extensions",
+			owner: Token2022ProgramID,
+			data:  extAccount,
+			want: &TokenAccount{Mint: testAddress(2), Owner: testAddress(3), Amount: 1_234_500,
+				State: StateInitialized, CloseAuthority: testAddress(4),
+				Extensions: []Extension{&ImmutableOwner{}, &TransferFeeAmount{WithheldAmount: 42}}},
+		},
+		{
+			name:  "truncated extension",
+			owner: Token2022ProgramID,
+			data:  extMint[:len(extMint)-1],
+			err:   ErrShortData,
+		},
+		{
+			name:  "extension area of the wrong account type",
+			owner: Token2022ProgramID,
+			data:  concat(account, []byte{byte(accountTypeUninitialized)}, tlv(ExtensionImmutableOwner, nil)),
+			err:   ErrUnknownLayout,
+		},
+		{
+			name:  "extended account under the legacy program",
+			owner: TokenProgramID,
+			data:  extAccount,
+			err:   ErrUnknownLayout,
+		},
+		{
+			name:  "other owner",
+			owner: "11111111111111111111111111111111",
+			data:  mint,
+			err:   ErrNotTokenProgram,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeToken(tt.owner, tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("DecodeToken error = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeToken = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransferFee(t *testing.T) {
+	config := &TransferFeeConfig{
+		OlderTransferFee: TransferFee{Epoch: 1, MaximumFee: 1000, BasisPoints: 50},
+		NewerTransferFee: TransferFee{Epoch: 3, MaximumFee: 500, BasisPoints: 100},
+	}
+	tests := []struct {
+		epoch, amount, fee uint64
+	}{
+		{2, 0, 0},
+		{2, 1, 1},            // rounded up
+		{2, 12_345, 62},      // 61.725
+		{2, 1_000_000, 1000}, // capped
+		{3, 10_000, 100},
+		{3, 1 << 63, 500},
+	}
+	for _, tt := range tests {
+		if got := config.FeeAt(tt.epoch).Fee(tt.amount); got != tt.fee {
+			t.Errorf("fee on %d at epoch %d = %d, want %d", tt.amount, tt.epoch, got, tt.fee)
+		}
+	}
+}
+
+func TestUIAmountString(t *testing.T) {
+	tests := []struct {
+		amount   uint64
+		decimals uint8
+		want     string
+	}{
+		{5, 0, "5"},
+		{100, 2, "1"},
+		{1_234_500, 6, "1.2345"},
+		{7, 9, "0.000000007"},
+		{0, 6, "0"},
+	}
+	for _, tt := range tests {
+		if got := UIAmountString(tt.amount, tt.decimals); got != tt.want {
+			t.Errorf("UIAmountString(%d, %d) = %q, want %q", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}
@@ -77,7 +77,7 @@ func subscribeToTransactions(ctx context.Context, client *thorclient.Client) {
 
 	log.Println("Subscribed to transactions")
 	for {
-		msg, err := stream.Recv()
+		tx, err := stream.Recv()
 		if err != nil {
 			if thorclient.IsStreamDone(err) {
 				log.Println("Transaction stream closed")
@@ -87,11 +87,9 @@ func subscribeToTransactions(ctx context.Context, client *thorclient.Client) {
 			return
 		}
 
-		if tx := msg.GetTransaction(); tx != nil {
-			log.Printf("Received transaction: slot=%d, signature=%x",
-				tx.Transaction.Slot,
-				tx.Transaction.Signature[:8])
-		}
+		log.Printf("Received transaction: slot=%d, signature=%x",
+			tx.Slot,
+			tx.Signature[:8])
 	}
 }
 
@@ -103,22 +101,16 @@ func subscribeToSlots(ctx context.Context, client *thorclient.Client) {
 	}
 
 	log.Println("Subscribed to slots")
-	for {
-		msg, err := stream.Recv()
+	for slot, err := range stream.All() {
 		if err != nil {
-			if thorclient.IsStreamDone(err) {
-				log.Println("Slot stream closed")
-				return
-			}
 			log.Printf("Error receiving slot: %v", err)
 			return
 		}
 
-		if slot := msg.GetSlot(); slot != nil {
-			log.Printf("Received slot: slot=%d, status=%d, height=%d",
-				slot.Slot, slot.Status, slot.BlockHeight)
-		}
+		log.Printf("Received slot: slot=%d, status=%d, height=%d",
+			slot.Slot, slot.Status, slot.BlockHeight)
 	}
+	log.Println("Slot stream closed")
 }
 
 func subscribeToWallets(ctx context.Context, client *thorclient.Client, wallets []string) {
@@ -130,7 +122,7 @@ func subscribeToWallets(ctx context.Context, client *thorclient.Client, wallets
 
 	log.Printf("Subscribed to %d wallets", len(wallets))
 	for {
-		msg, err := stream.Recv()
+		tx, err := stream.Recv()
 		if err != nil {
 			if thorclient.IsStreamDone(err) {
 				log.Println("Wallet stream closed")
@@ -140,11 +132,9 @@ func subscribeToWallets(ctx context.Context, client *thorclient.Client, wallets
 			return
 		}
 
-		if tx := msg.GetTransaction(); tx != nil {
-			log.Printf("Received wallet transaction: slot=%d, signature=%x",
-				tx.Transaction.Slot,
-				tx.Transaction.Signature[:8])
-		}
+		log.Printf("Received wallet transaction: slot=%d, signature=%x",
+			tx.Slot,
+			tx.Signature[:8])
 	}
 }
 
@@ -157,7 +147,7 @@ func subscribeToAccounts(ctx context.Context, client *thorclient.Client, account
 
 	log.Printf("Subscribed to %d accounts and %d owners", len(accounts), len(owners))
 	for {
-		msg, err := stream.Recv()
+		acc, err := stream.Recv()
 		if err != nil {
 			if thorclient.IsStreamDone(err) {
 				log.Println("Account stream closed")
@@ -167,10 +157,8 @@ func subscribeToAccounts(ctx context.Context, client *thorclient.Client, account
 			return
 		}
 
-		if acc := msg.GetAccountUpdate(); acc != nil {
-			log.Printf("Received account update: pubkey=%x, lamports=%d",
-				acc.Pubkey[:8], acc.Lamports)
-		}
+		log.Printf("Received account update: pubkey=%x, lamports=%d",
+			acc.Pubkey[:8], acc.Lamports)
 	}
 }
 